@@ -0,0 +1,147 @@
+package backup
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// SpaceBackups holds the backups of one space, or the error the broker returned for it.
+type SpaceBackups struct {
+	Space   guidTranslator.SpaceInfo
+	Backups []BackupRecord
+	Err     error
+}
+
+// GetBackupRecordsOfSpaces lists the backups of the given spaces with at most constants.MaxConcurrentRequests
+// concurrent broker requests. The result keeps the order of the spaces.
+func GetBackupRecordsOfSpaces(client *http.Client, spaces []guidTranslator.SpaceInfo) []SpaceBackups {
+	results := make([]SpaceBackups, len(spaces))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, constants.MaxConcurrentRequests)
+
+	for index, space := range spaces {
+		wg.Add(1)
+		go func(index int, space guidTranslator.SpaceInfo) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			records, err := GetBackupRecords(client, space.Guid, "")
+			<-semaphore
+
+			results[index] = SpaceBackups{Space: space, Backups: records, Err: err}
+		}(index, space)
+	}
+	wg.Wait()
+	return results
+}
+
+// ListBackupsAcrossSpaces lists the backups of all spaces of the targeted org, or of all orgs visible to the user if allOrgs is set.
+// Spaces whose backups cannot be listed, e.g. because the user lacks permissions there, are reported after the list
+// and fail the command.
+func (c *BackupCommand) ListBackupsAcrossSpaces(cliConnection plugin.CliConnection, allOrgs bool, noInstanceNames bool) {
+	var orgGuid string
+	if allOrgs {
+		fmt.Println("Getting the list of backups in all orgs ...")
+	} else {
+		orgGuid = helper.GetOrgGUID(helper.ReadConfigJsonFile())
+		fmt.Println("Getting the list of backups in all spaces of the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
+	}
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	client := GetHttpClient()
+
+	spaces, err := guidTranslator.FindSpaces(cliConnection, orgGuid)
+	if err != nil {
+		errors.CfCliPluginError("/v2/spaces")
+	}
+	SortSpaces(spaces)
+
+	var instanceNames map[string]string
+	if !noInstanceNames {
+		instanceNames, err = guidTranslator.FindInstanceNames(cliConnection)
+		if err != nil {
+			errors.CfCliPluginError("/v2/service_instances")
+		}
+	}
+
+	listed, failed := PartitionSpaceBackups(GetBackupRecordsOfSpaces(client, spaces))
+	if len(failed) == 0 {
+		fmt.Println(AddColor("OK", constants.Green))
+	}
+
+	var instanceColumn string = "instance_name"
+	if noInstanceNames {
+		instanceColumn = "instance_guid"
+	}
+	table := NewTable()
+	table.SetColWidth(40)
+	table.SetHeader([]string{AddColor("org", constants.White), AddColor("space", constants.White), AddColor("backup_guid", constants.White), AddColor(instanceColumn, constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White), AddColor("duration", constants.White), AddColor(" ", constants.White)})
+
+	for _, result := range listed {
+		for _, record := range result.Backups {
+			var instance string = record.InstanceGuid
+			var status string
+			if !noInstanceNames {
+				if name, flag := instanceNames[record.InstanceGuid]; flag {
+					instance = name
+				} else {
+					status = "Status: Instance already deleted"
+				}
+			}
+			var finishedAt string = record.FinishedAt
+			if finishedAt == "" {
+				finishedAt = "null"
+			}
+			table.Append([]string{result.Space.OrgName, result.Space.Name, AddColor(record.BackupGuid, constants.Cyan), instance, record.Type, record.Trigger, record.StartedAt, finishedAt, helper.FormatDuration(record.StartedAt, record.FinishedAt), status})
+		}
+	}
+	table.Render()
+
+	if len(failed) > 0 {
+		fmt.Println()
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println("Partial result: the backups of " + strconv.Itoa(len(failed)) + " of " + strconv.Itoa(len(spaces)) + " space(s) could not be listed.")
+		for _, result := range failed {
+			fmt.Println(" ", result.Space.OrgName, "/", result.Space.Name+":", result.Err)
+		}
+		os.Exit(1)
+	}
+}
+
+// SortSpaces sorts the spaces by org name and space name.
+func SortSpaces(spaces []guidTranslator.SpaceInfo) {
+	sort.Slice(spaces, func(i, j int) bool {
+		if spaces[i].OrgName != spaces[j].OrgName {
+			return spaces[i].OrgName < spaces[j].OrgName
+		}
+		return spaces[i].Name < spaces[j].Name
+	})
+}
+
+// PartitionSpaceBackups splits the results of GetBackupRecordsOfSpaces into the spaces whose backups were listed, with
+// their backups sorted by SortBackupRecords, and the spaces whose backups could not be listed. Both keep the order of the spaces.
+func PartitionSpaceBackups(results []SpaceBackups) ([]SpaceBackups, []SpaceBackups) {
+	var listed []SpaceBackups
+	var failed []SpaceBackups
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+			continue
+		}
+		SortBackupRecords(result.Backups)
+		listed = append(listed, result)
+	}
+	return listed, failed
+}
@@ -0,0 +1,34 @@
+package backup
+
+import (
+	"fmt"
+
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backups across spaces", func() {
+	It("The spaces should be sorted by org and space name", func() {
+		spaces := []guidTranslator.SpaceInfo{{Name: "live", OrgName: "prod"}, {Name: "test", OrgName: "demo"}, {Name: "dev", OrgName: "demo"}}
+		SortSpaces(spaces)
+		Expect(spaces).To(Equal([]guidTranslator.SpaceInfo{{Name: "dev", OrgName: "demo"}, {Name: "test", OrgName: "demo"}, {Name: "live", OrgName: "prod"}}))
+	})
+	It("The listed spaces should be separated from the failed ones in the order of the spaces", func() {
+		results := []SpaceBackups{
+			{Space: guidTranslator.SpaceInfo{Guid: "s1"}, Backups: []BackupRecord{
+				{BackupGuid: "b1", StartedAt: "2018-11-27T00:00:00Z"},
+				{BackupGuid: "b2", StartedAt: "2018-11-28T00:00:00Z"},
+			}},
+			{Space: guidTranslator.SpaceInfo{Guid: "s2"}, Err: fmt.Errorf("not authorized")},
+			{Space: guidTranslator.SpaceInfo{Guid: "s3"}},
+		}
+		listed, failed := PartitionSpaceBackups(results)
+		Expect(listed).To(HaveLen(2))
+		Expect(listed[0].Space.Guid).To(Equal("s1"))
+		Expect(listed[0].Backups[0].BackupGuid).To(Equal("b2"))
+		Expect(listed[1].Space.Guid).To(Equal("s3"))
+		Expect(failed).To(HaveLen(1))
+		Expect(failed[0].Space.Guid).To(Equal("s2"))
+	})
+})
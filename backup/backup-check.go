@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+const (
+	ComplianceOK        string = "OK"
+	ComplianceViolation string = "VIOLATION"
+	ComplianceUnknown   string = "UNKNOWN"
+)
+
+// ComplianceResult is the outcome of checking the newest successful backup of one instance against the maximum age.
+type ComplianceResult struct {
+	InstanceGuid     string `json:"instance_guid"`
+	InstanceName     string `json:"instance_name"`
+	Service          string `json:"service"`
+	Status           string `json:"status"`
+	Reason           string `json:"reason,omitempty"`
+	LastBackupGuid   string `json:"last_backup_guid,omitempty"`
+	LastBackupAt     string `json:"last_backup_at,omitempty"`
+	LastBackupAgeSec int64  `json:"last_backup_age_seconds,omitempty"`
+}
+
+// CheckBackupCompliance compares the newest succeeded backup of the instance, optionally only of the required backup type,
+// with the maximum age.
+func CheckBackupCompliance(instance guidTranslator.InstanceInfo, records []BackupRecord, maxAge time.Duration, requireType string, now time.Time) ComplianceResult {
+	result := ComplianceResult{InstanceGuid: instance.Guid, InstanceName: instance.Name, Service: instance.ServiceLabel}
+
+	var newest *BackupRecord
+	for index, record := range records {
+		if record.InstanceGuid != instance.Guid || !record.IsSucceeded() {
+			continue
+		}
+		if requireType != "" && record.Type != requireType {
+			continue
+		}
+		if newest == nil || record.StartTime().After(newest.StartTime()) {
+			newest = &records[index]
+		}
+	}
+
+	if newest == nil {
+		result.Status = ComplianceViolation
+		if requireType != "" {
+			result.Reason = "no successful " + requireType + " backup"
+		} else {
+			result.Reason = "no successful backup"
+		}
+		return result
+	}
+
+	age := now.Sub(newest.StartTime())
+	result.LastBackupGuid = newest.BackupGuid
+	result.LastBackupAt = newest.StartedAt
+	result.LastBackupAgeSec = int64(age.Seconds())
+	if age > maxAge {
+		result.Status = ComplianceViolation
+		result.Reason = "last successful backup is older than " + maxAge.String()
+	} else {
+		result.Status = ComplianceOK
+	}
+	return result
+}
+
+// BackupCheck checks whether the newest successful backup of the given instance, or of all instances of supported services
+// in the space if serviceInstanceName is empty, is younger than maxAge. It returns constants.CheckExitOK if all instances
+// comply, constants.CheckExitViolations if at least one does not and constants.CheckExitUnknown if the backups could not be
+// determined. The login and conf.json are checked up front, so that their failures return constants.CheckExitUnknown too.
+func (c *BackupCommand) BackupCheck(cliConnection plugin.CliConnection, serviceInstanceName string, maxAge time.Duration, requireType string, jsonOutput bool) (status int) {
+	defer func() {
+		if r := recover(); r != nil {
+			status = failBackupCheck(fmt.Errorf("%v", r), jsonOutput)
+		}
+	}()
+	if !helper.IsLoggedIn() {
+		return failBackupCheck(fmt.Errorf("no access token was found, you may be logged out"), jsonOutput)
+	}
+	if _, err := helper.LoadConfiguration(); err != nil {
+		return failBackupCheck(fmt.Errorf("invalid plugin configuration in %s: %v", helper.GetConfFilePath(), err), jsonOutput)
+	}
+
+	if !jsonOutput {
+		fmt.Println("Checking the backups in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "against a maximum age of", AddColor(maxAge.String(), constants.Cyan), "...")
+	}
+
+	client := GetHttpClient()
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+
+	instances, err := guidTranslator.FindSpaceInstances(cliConnection, userSpaceGuid)
+	if err != nil {
+		return failBackupCheck(err, jsonOutput)
+	}
+
+	var eligible []guidTranslator.InstanceInfo
+	for _, instance := range instances {
+		if serviceInstanceName != "" && instance.Name != serviceInstanceName {
+			continue
+		}
+		if !guidTranslator.IsServiceNameValid(instance.ServiceLabel) {
+			if serviceInstanceName != "" {
+				return failBackupCheck(fmt.Errorf("service instance %s is of service %s, which is not supported", instance.Name, instance.ServiceLabel), jsonOutput)
+			}
+			continue
+		}
+		eligible = append(eligible, instance)
+	}
+	if serviceInstanceName != "" && len(eligible) == 0 {
+		return failBackupCheck(fmt.Errorf("service instance %s doesn't exist", serviceInstanceName), jsonOutput)
+	}
+	sort.Slice(eligible, func(i, j int) bool {
+		return eligible[i].Name < eligible[j].Name
+	})
+
+	var results []ComplianceResult
+	records, err := GetBackupRecords(client, userSpaceGuid, "")
+	now := time.Now()
+	for _, instance := range eligible {
+		if err != nil {
+			results = append(results, ComplianceResult{InstanceGuid: instance.Guid, InstanceName: instance.Name, Service: instance.ServiceLabel, Status: ComplianceUnknown, Reason: err.Error()})
+			continue
+		}
+		results = append(results, CheckBackupCompliance(instance, records, maxAge, requireType, now))
+	}
+
+	var exitCode int = constants.CheckExitOK
+	for _, result := range results {
+		if result.Status == ComplianceUnknown {
+			exitCode = constants.CheckExitUnknown
+			break
+		}
+		if result.Status == ComplianceViolation {
+			exitCode = constants.CheckExitViolations
+		}
+	}
+
+	if jsonOutput {
+		if results == nil {
+			results = []ComplianceResult{}
+		}
+		output, _ := json.MarshalIndent(results, "", "  ")
+		fmt.Println(string(output))
+		return exitCode
+	}
+
+	if exitCode == constants.CheckExitOK {
+		fmt.Println(AddColor("OK", constants.Green))
+	} else {
+		fmt.Println(AddColor("FAILED", constants.Red))
+	}
+	table := NewTable()
+	table.SetHeader([]string{AddColor("instance_name", constants.White), AddColor("service", constants.White), AddColor("status", constants.White), AddColor("last_backup_at", constants.White), AddColor("age", constants.White), AddColor("reason", constants.White)})
+	for _, result := range results {
+		var status string = AddColor(result.Status, constants.Green)
+		if result.Status != ComplianceOK {
+			status = AddColor(result.Status, constants.Red)
+		}
+		var age string
+		if result.LastBackupAt != "" {
+			age = (time.Duration(result.LastBackupAgeSec) * time.Second).String()
+		}
+		table.Append([]string{result.InstanceName, result.Service, status, result.LastBackupAt, age, result.Reason})
+	}
+	table.Render()
+	return exitCode
+}
+
+// failBackupCheck reports an error which prevents the check and returns constants.CheckExitUnknown.
+func failBackupCheck(err error, jsonOutput bool) int {
+	if !jsonOutput {
+		fmt.Println(AddColor("FAILED", constants.Red))
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return constants.CheckExitUnknown
+}
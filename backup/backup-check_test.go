@@ -0,0 +1,36 @@
+package backup
+
+import (
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup check", func() {
+	now, _ := time.Parse(time.RFC3339, "2018-11-30T00:00:00Z")
+	instance := guidTranslator.InstanceInfo{Guid: "i1", Name: "demo-blueprint", ServiceLabel: "blueprint"}
+
+	records := []BackupRecord{
+		{BackupGuid: "b1", InstanceGuid: "i1", Type: "online", State: "succeeded", StartedAt: "2018-11-28T00:00:00Z"},
+		{BackupGuid: "b2", InstanceGuid: "i1", Type: "offline", State: "succeeded", StartedAt: "2018-11-29T12:00:00Z"},
+		{BackupGuid: "b3", InstanceGuid: "i1", Type: "online", State: "failed", StartedAt: "2018-11-29T18:00:00Z"},
+	}
+
+	It("An instance with a recent successful backup should comply", func() {
+		result := CheckBackupCompliance(instance, records, 24*time.Hour, "", now)
+		Expect(result.Status).To(Equal(ComplianceOK))
+		Expect(result.LastBackupGuid).To(Equal("b2"))
+	})
+	It("Only backups of the required type should count", func() {
+		result := CheckBackupCompliance(instance, records, 24*time.Hour, "online", now)
+		Expect(result.Status).To(Equal(ComplianceViolation))
+		Expect(result.LastBackupGuid).To(Equal("b1"))
+	})
+	It("An instance without a successful backup should be a violation", func() {
+		result := CheckBackupCompliance(guidTranslator.InstanceInfo{Guid: "i2"}, records, 24*time.Hour, "", now)
+		Expect(result.Status).To(Equal(ComplianceViolation))
+		Expect(result.LastBackupGuid).To(BeEmpty())
+	})
+})
@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// InstanceLabels are the labels identifying an instance in the exported metrics.
+type InstanceLabels struct {
+	InstanceGuid string
+	InstanceName string
+	Service      string
+	Org          string
+	Space        string
+}
+
+func (labels InstanceLabels) format(extra ...string) string {
+	pairs := []string{
+		"instance_guid", labels.InstanceGuid,
+		"instance_name", labels.InstanceName,
+		"service", labels.Service,
+		"org", labels.Org,
+		"space", labels.Space,
+	}
+	pairs = append(pairs, extra...)
+
+	var parts []string
+	for i := 0; i < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+"=\""+escapeLabelValue(pairs[i+1])+"\"")
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func escapeLabelValue(value string) string {
+	value = strings.Replace(value, "\\", "\\\\", -1)
+	value = strings.Replace(value, "\"", "\\\"", -1)
+	return strings.Replace(value, "\n", "\\n", -1)
+}
+
+// FormatBackupMetrics renders the backup summaries in the Prometheus text exposition format. activities are the operations
+// in progress on the instances, see FindInstanceActivity; for instances without an entry the backups in progress are used.
+func FormatBackupMetrics(summaries map[string]*InstanceBackups, labels map[string]InstanceLabels, activities map[string][]string) string {
+	var guids []string
+	for guid := range summaries {
+		guids = append(guids, guid)
+	}
+	sort.Strings(guids)
+
+	var buffer bytes.Buffer
+	gauge := func(name string, help string, value func(summary *InstanceBackups, labels InstanceLabels) (string, float64, bool)) {
+		fmt.Fprintf(&buffer, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, guid := range guids {
+			if labelString, metric, flag := value(summaries[guid], labels[guid]); flag {
+				fmt.Fprintf(&buffer, "%s%s %s\n", name, labelString, strconv.FormatFloat(metric, 'f', -1, 64))
+			}
+		}
+	}
+
+	gauge("servicefabrik_backup_last_success_timestamp_seconds", "Start time of the newest successful backup.", func(summary *InstanceBackups, labels InstanceLabels) (string, float64, bool) {
+		if summary.NewestSucceeded == nil {
+			return "", 0, false
+		}
+		return labels.format(), float64(summary.NewestSucceeded.StartTime().Unix()), true
+	})
+	gauge("servicefabrik_backup_last_duration_seconds", "Duration of the newest finished backup.", func(summary *InstanceBackups, labels InstanceLabels) (string, float64, bool) {
+		for _, record := range summary.Backups {
+			if duration, flag := record.Duration(); flag {
+				return labels.format(), duration.Seconds(), true
+			}
+		}
+		return "", 0, false
+	})
+	gauge("servicefabrik_backup_last_state", "State of the newest backup, given by the state label.", func(summary *InstanceBackups, labels InstanceLabels) (string, float64, bool) {
+		return labels.format("state", summary.Newest.State), 1, true
+	})
+	gauge("servicefabrik_backup_count", "Number of backups.", func(summary *InstanceBackups, labels InstanceLabels) (string, float64, bool) {
+		return labels.format(), float64(summary.Count), true
+	})
+	gauge("servicefabrik_backup_in_progress", "Whether an update, backup or restore is currently in progress on the instance.", func(summary *InstanceBackups, labels InstanceLabels) (string, float64, bool) {
+		activity, flag := activities[labels.InstanceGuid]
+		if !flag {
+			activity = DescribeActivity(nil, summary.Backups, nil)
+		}
+		if len(activity) > 0 {
+			return labels.format(), 1, true
+		}
+		return labels.format(), 0, true
+	})
+	return buffer.String()
+}
+
+// WriteBackupMetrics writes the backup metrics of all instances of the space to the given file for the node_exporter textfile collector.
+// The file is replaced atomically.
+func (c *BackupCommand) WriteBackupMetrics(cliConnection plugin.CliConnection, path string) {
+	fmt.Println("Writing the backup metrics of the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "to", AddColor(path, constants.Cyan), "...")
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	client := GetHttpClient()
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+
+	records, err := GetBackupRecords(client, userSpaceGuid, "")
+	if err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	instanceNames, err := guidTranslator.FindInstanceNames(cliConnection)
+	if err != nil {
+		errors.CfCliPluginError("/v2/service_instances")
+	}
+
+	var orgName string = helper.GetOrgName(helper.ReadConfigJsonFile())
+	var spaceName string = helper.GetSpaceName(helper.ReadConfigJsonFile())
+	summaries := SummarizeBackups(records)
+	serviceNames := make(map[string]string)
+	labels := make(map[string]InstanceLabels)
+	activities := make(map[string][]string)
+	for guid, summary := range summaries {
+		if _, flag := instanceNames[guid]; flag { //Deleted instances have no last operation or restore.
+			if activity, err := FindInstanceActivity(cliConnection, client, guid, userSpaceGuid); err == nil {
+				activities[guid] = activity
+			} else {
+				fmt.Fprintln(os.Stderr, "Cannot check the operations in progress on", instanceNames[guid]+", using its backups only:", err)
+			}
+		}
+		serviceId := summary.Newest.ServiceId
+		if _, flag := serviceNames[serviceId]; !flag {
+			serviceNames[serviceId] = strings.Trim(guidTranslator.FindServiceName(cliConnection, serviceId, nil), "\"")
+		}
+		labels[guid] = InstanceLabels{
+			InstanceGuid: guid,
+			InstanceName: instanceNames[guid],
+			Service:      serviceNames[serviceId],
+			Org:          orgName,
+			Space:        spaceName,
+		}
+	}
+
+	if err := helper.WriteFileAtomic(path, []byte(FormatBackupMetrics(summaries, labels, activities)), 0644); err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(AddColor("OK", constants.Green))
+}
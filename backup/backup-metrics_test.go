@@ -0,0 +1,36 @@
+package backup
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup metrics", func() {
+	records := []BackupRecord{
+		{BackupGuid: "b1", InstanceGuid: "i1", State: "succeeded", StartedAt: "2018-11-28T00:00:00Z", FinishedAt: "2018-11-28T00:05:00Z"},
+		{BackupGuid: "b2", InstanceGuid: "i1", State: "processing", StartedAt: "2018-11-29T00:00:00Z"},
+	}
+	labels := map[string]InstanceLabels{"i1": {InstanceGuid: "i1", InstanceName: "demo \"blueprint\"", Service: "blueprint", Org: "org", Space: "dev"}}
+	metrics := FormatBackupMetrics(SummarizeBackups(records), labels, nil)
+	labelString := `{instance_guid="i1",instance_name="demo \"blueprint\"",service="blueprint",org="org",space="dev"}`
+
+	It("The gauges should be written in the exposition format", func() {
+		Expect(metrics).To(ContainSubstring("# TYPE servicefabrik_backup_count gauge\n"))
+		Expect(metrics).To(ContainSubstring("servicefabrik_backup_count" + labelString + " 2\n"))
+		Expect(metrics).To(ContainSubstring("servicefabrik_backup_last_success_timestamp_seconds" + labelString + " 1543363200\n"))
+	})
+	It("The newest finished backup should give the duration", func() {
+		Expect(metrics).To(ContainSubstring("servicefabrik_backup_last_duration_seconds" + labelString + " 300\n"))
+	})
+	It("The state and the running operation of the newest backup should be exported", func() {
+		Expect(metrics).To(ContainSubstring(`servicefabrik_backup_last_state{instance_guid="i1",instance_name="demo \"blueprint\"",service="blueprint",org="org",space="dev",state="processing"} 1`))
+		Expect(metrics).To(ContainSubstring("servicefabrik_backup_in_progress" + labelString + " 1\n"))
+	})
+	It("A running update or restore should count as in progress, not only a backup", func() {
+		finished := []BackupRecord{{BackupGuid: "b1", InstanceGuid: "i1", State: "succeeded", StartedAt: "2018-11-28T00:00:00Z"}}
+		idle := FormatBackupMetrics(SummarizeBackups(finished), labels, map[string][]string{"i1": nil})
+		Expect(idle).To(ContainSubstring("servicefabrik_backup_in_progress" + labelString + " 0\n"))
+		restoring := FormatBackupMetrics(SummarizeBackups(finished), labels, map[string][]string{"i1": {"restore processing since 2018-11-29T00:00:00Z"}})
+		Expect(restoring).To(ContainSubstring("servicefabrik_backup_in_progress" + labelString + " 1\n"))
+	})
+})
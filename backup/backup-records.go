@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/olekukonko/tablewriter"
+)
+
+// BackupRecord is a single entry of the broker /backups listing.
+type BackupRecord struct {
+	BackupGuid   string `json:"backup_guid"`
+	InstanceGuid string `json:"instance_guid"`
+	ServiceId    string `json:"service_id"`
+	PlanId       string `json:"plan_id"`
+	Username     string `json:"username"`
+	Type         string `json:"type"`
+	Trigger      string `json:"trigger"`
+	State        string `json:"state"`
+	StartedAt    string `json:"started_at"`
+	FinishedAt   string `json:"finished_at"`
+}
+
+func (record BackupRecord) StartTime() time.Time {
+	startTime, _ := time.Parse(time.RFC3339, record.StartedAt)
+	return startTime
+}
+
+// Duration returns how long the backup took. The second result is false while the backup has not finished.
+func (record BackupRecord) Duration() (time.Duration, bool) {
+	finishTime, err := time.Parse(time.RFC3339, record.FinishedAt)
+	if err != nil || record.StartedAt == "" {
+		return 0, false
+	}
+	return finishTime.Sub(record.StartTime()), true
+}
+
+func (record BackupRecord) IsSucceeded() bool {
+	return record.State == constants.BackupStateSucceeded
+}
+
+func (record BackupRecord) IsInProgress() bool {
+	return record.State == constants.BackupStateProcessing || record.State == constants.BackupStateAborting
+}
+
+// SortBackupRecords orders the records newest first.
+func SortBackupRecords(records []BackupRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].StartTime().After(records[j].StartTime())
+	})
+}
+
+// InstanceBackups summarizes the backups of one service instance.
+type InstanceBackups struct {
+	InstanceGuid    string
+	Count           int
+	Newest          BackupRecord
+	NewestSucceeded *BackupRecord
+	Backups         []BackupRecord
+}
+
+// SummarizeBackups groups the records by instance guid.
+func SummarizeBackups(records []BackupRecord) map[string]*InstanceBackups {
+	SortBackupRecords(records)
+
+	summaries := make(map[string]*InstanceBackups)
+	for _, record := range records {
+		summary, flag := summaries[record.InstanceGuid]
+		if !flag {
+			summary = &InstanceBackups{InstanceGuid: record.InstanceGuid, Newest: record}
+			summaries[record.InstanceGuid] = summary
+		}
+		summary.Count++
+		summary.Backups = append(summary.Backups, record)
+		if summary.NewestSucceeded == nil && record.IsSucceeded() {
+			newestSucceeded := record
+			summary.NewestSucceeded = &newestSucceeded
+		}
+	}
+	return summaries
+}
+
+// NewTable returns a table writer with the borderless layout used by all list views.
+func NewTable() *tablewriter.Table {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetBorder(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetCenterSeparator(" ")
+	table.SetColumnSeparator(" ")
+	table.SetRowSeparator(" ")
+	table.SetHeaderLine(false)
+	table.SetAutoFormatHeaders(false)
+	return table
+}
+
+func getBrokerApiUrl() string {
+	return helper.GetBrokerApiUrl()
+}
+
+// GetBackupRecords lists the backups of the given space. If instanceGuid is not empty, only the backups of that instance are returned.
+func GetBackupRecords(client *http.Client, userSpaceGuid string, instanceGuid string) ([]BackupRecord, error) {
+	var url string = getBrokerApiUrl() + "/backups" + "?space_guid=" + userSpaceGuid
+	if instanceGuid != "" {
+		url = url + "&instance_id=" + instanceGuid
+	}
+
+	resp, body, err := helper.CallBroker(client, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Status != constants.OKHttpStatusResponse {
+		return nil, helper.BrokerError(resp, body)
+	}
+
+	var records []BackupRecord
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// DeleteBackupRecord deletes a single backup without asking for confirmation.
+func DeleteBackupRecord(client *http.Client, userSpaceGuid string, backupGuid string) error {
+	var url string = getBrokerApiUrl() + "/backups/" + backupGuid + "?space_guid=" + userSpaceGuid
+
+	resp, body, err := helper.CallBroker(client, "DELETE", url, nil)
+	if err != nil {
+		return err
+	}
+	if resp.Status != constants.OKHttpStatusResponse {
+		return helper.BrokerError(resp, body)
+	}
+	return nil
+}
+
+// findStartedBackup returns a check for helper.StartBrokerOperation which finds an on-demand backup of the instance
+// started by the user since the first attempt.
+func findStartedBackup(client *http.Client, userSpaceGuid string, instanceGuid string) func(time.Time) (*helper.StartedOperation, error) {
+	return func(since time.Time) (*helper.StartedOperation, error) {
+		records, err := GetBackupRecords(client, userSpaceGuid, instanceGuid)
+		if err != nil {
+			return nil, err
+		}
+		var username string = helper.NewTokenInfo(helper.GetAccessToken(helper.ReadConfigJsonFile())).Username
+		SortBackupRecords(records)
+		for _, record := range records {
+			if record.Trigger == constants.BackupTriggerOnDemand && record.Username == username && helper.StartedSince(record.StartedAt, since) {
+				return &helper.StartedOperation{Guid: record.BackupGuid, StartedAt: record.StartedAt}, nil
+			}
+		}
+		return nil, nil
+	}
+}
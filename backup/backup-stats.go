@@ -0,0 +1,176 @@
+package backup
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// BackupStats are the statistics of the backups of one instance, either of all types or of one backup type.
+// The durations are computed from the Measured succeeded backups which have finished; Trend holds the last durations, oldest first.
+type BackupStats struct {
+	InstanceGuid string
+	Type         string
+	Count        int
+	Succeeded    int
+	Measured     int
+	Min          time.Duration
+	Median       time.Duration
+	P95          time.Duration
+	Max          time.Duration
+	Trend        []time.Duration
+}
+
+// SuccessRate returns the share of succeeded backups in percent.
+func (stats BackupStats) SuccessRate() float64 {
+	if stats.Count == 0 {
+		return 0
+	}
+	return 100 * float64(stats.Succeeded) / float64(stats.Count)
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+func computeStats(instanceGuid string, backupType string, records []BackupRecord, trendLength int) BackupStats {
+	stats := BackupStats{InstanceGuid: instanceGuid, Type: backupType, Count: len(records)}
+
+	var durations []time.Duration
+	for index := len(records) - 1; index >= 0; index-- {
+		if !records[index].IsSucceeded() {
+			continue
+		}
+		stats.Succeeded++
+		if duration, flag := records[index].Duration(); flag {
+			durations = append(durations, duration)
+		}
+	}
+
+	stats.Measured = len(durations)
+	if len(durations) > trendLength {
+		stats.Trend = durations[len(durations)-trendLength:]
+	} else {
+		stats.Trend = durations
+	}
+
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	if len(sorted) > 0 {
+		stats.Min = sorted[0]
+		stats.Max = sorted[len(sorted)-1]
+	}
+	stats.Median = percentile(sorted, 0.5)
+	stats.P95 = percentile(sorted, 0.95)
+	return stats
+}
+
+// ComputeBackupStats computes the statistics of the backups started after since. For every instance the statistics over all
+// backup types come first, followed by the statistics per backup type. Instances are ordered by guid.
+func ComputeBackupStats(records []BackupRecord, since time.Time, trendLength int) []BackupStats {
+	var window []BackupRecord
+	for _, record := range records {
+		if since.IsZero() || record.StartTime().After(since) {
+			window = append(window, record)
+		}
+	}
+
+	summaries := SummarizeBackups(window)
+	var guids []string
+	for guid := range summaries {
+		guids = append(guids, guid)
+	}
+	sort.Strings(guids)
+
+	var result []BackupStats
+	for _, guid := range guids {
+		backups := summaries[guid].Backups
+		result = append(result, computeStats(guid, "all", backups, trendLength))
+
+		byType := make(map[string][]BackupRecord)
+		var types []string
+		for _, record := range backups {
+			if _, flag := byType[record.Type]; !flag {
+				types = append(types, record.Type)
+			}
+			byType[record.Type] = append(byType[record.Type], record)
+		}
+		sort.Strings(types)
+		for _, backupType := range types {
+			result = append(result, computeStats(guid, backupType, byType[backupType], trendLength))
+		}
+	}
+	return result
+}
+
+func formatStatsDuration(duration time.Duration, measured int) string {
+	if measured == 0 {
+		return "null"
+	}
+	return duration.Round(time.Second).String()
+}
+
+// ShowBackupStats shows the backup statistics of the given instance, or of all instances in the space if serviceInstanceName is empty,
+// for the backups started within the window.
+func (c *BackupCommand) ShowBackupStats(cliConnection plugin.CliConnection, serviceInstanceName string, window time.Duration, trendLength int) {
+	fmt.Println("Computing backup statistics of the last", AddColor(window.String(), constants.Cyan), "in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	client := GetHttpClient()
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	var instanceGuid string
+	if serviceInstanceName != "" {
+		instanceGuid, _, _ = guidTranslator.ResolveInstance(cliConnection, serviceInstanceName, "", false)
+	}
+
+	records, err := GetBackupRecords(client, userSpaceGuid, instanceGuid)
+	if err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	instanceNames, err := guidTranslator.FindInstanceNames(cliConnection)
+	if err != nil {
+		errors.CfCliPluginError("/v2/service_instances")
+	}
+
+	fmt.Println(AddColor("OK", constants.Green))
+	table := NewTable()
+	table.SetHeader([]string{AddColor("instance_name", constants.White), AddColor("type", constants.White), AddColor("count", constants.White), AddColor("success_rate", constants.White), AddColor("min", constants.White), AddColor("median", constants.White), AddColor("p95", constants.White), AddColor("max", constants.White), AddColor("trend (oldest first)", constants.White)})
+	for _, stats := range ComputeBackupStats(records, time.Now().Add(-window), trendLength) {
+		var name string = instanceNames[stats.InstanceGuid]
+		if name == "" {
+			name = stats.InstanceGuid
+		}
+		var trend []string
+		for _, duration := range stats.Trend {
+			trend = append(trend, duration.Round(time.Second).String())
+		}
+		table.Append([]string{name, stats.Type, strconv.Itoa(stats.Count), strconv.FormatFloat(stats.SuccessRate(), 'f', 1, 64) + "%", formatStatsDuration(stats.Min, stats.Measured), formatStatsDuration(stats.Median, stats.Measured), formatStatsDuration(stats.P95, stats.Measured), formatStatsDuration(stats.Max, stats.Measured), strings.Join(trend, " ")})
+	}
+	table.Render()
+}
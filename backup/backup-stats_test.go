@@ -0,0 +1,43 @@
+package backup
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup stats", func() {
+	records := []BackupRecord{
+		{BackupGuid: "b1", InstanceGuid: "i1", Type: "online", State: "succeeded", StartedAt: "2018-11-01T00:00:00Z", FinishedAt: "2018-11-01T00:10:00Z"},
+		{BackupGuid: "b2", InstanceGuid: "i1", Type: "online", State: "succeeded", StartedAt: "2018-11-10T00:00:00Z", FinishedAt: "2018-11-10T00:20:00Z"},
+		{BackupGuid: "b3", InstanceGuid: "i1", Type: "offline", State: "succeeded", StartedAt: "2018-11-20T00:00:00Z", FinishedAt: "2018-11-20T00:30:00Z"},
+		{BackupGuid: "b4", InstanceGuid: "i1", Type: "online", State: "failed", StartedAt: "2018-11-25T00:00:00Z", FinishedAt: "2018-11-25T00:01:00Z"},
+	}
+
+	It("Statistics over all types should come first", func() {
+		stats := ComputeBackupStats(records, time.Time{}, 2)
+		Expect(stats).To(HaveLen(3))
+		Expect(stats[0].Type).To(Equal("all"))
+		Expect(stats[0].Count).To(Equal(4))
+		Expect(stats[0].SuccessRate()).To(Equal(75.0))
+		Expect(stats[0].Min).To(Equal(10 * time.Minute))
+		Expect(stats[0].Median).To(Equal(20 * time.Minute))
+		Expect(stats[0].P95).To(Equal(30 * time.Minute))
+		Expect(stats[0].Max).To(Equal(30 * time.Minute))
+		Expect(stats[0].Trend).To(Equal([]time.Duration{20 * time.Minute, 30 * time.Minute}))
+	})
+	It("Statistics per backup type should follow", func() {
+		stats := ComputeBackupStats(records, time.Time{}, 5)
+		Expect(stats[1].Type).To(Equal("offline"))
+		Expect(stats[1].Count).To(Equal(1))
+		Expect(stats[2].Type).To(Equal("online"))
+		Expect(stats[2].Trend).To(Equal([]time.Duration{10 * time.Minute, 20 * time.Minute}))
+	})
+	It("Backups outside the window should be ignored", func() {
+		since, _ := time.Parse(time.RFC3339, "2018-11-05T00:00:00Z")
+		stats := ComputeBackupStats(records, since, 5)
+		Expect(stats[0].Count).To(Equal(3))
+		Expect(stats[0].Min).To(Equal(20 * time.Minute))
+	})
+})
@@ -1,8 +1,6 @@
 package backup
 
 import (
-	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -35,52 +33,20 @@ func AddColor(text string, textColor color.Attribute) string {
 	return printer(text)
 }
 
-type Configuration struct {
-	ServiceBroker       string
-	ServiceBrokerExtUrl string
-	SkipSslFlag         bool
-}
-
 func GetBrokerName() string {
-	return getConfiguration().ServiceBroker
+	return helper.GetConfiguration().ServiceBroker
 }
 
 func GetExtUrl() string {
-	return getConfiguration().ServiceBrokerExtUrl
+	return helper.GetConfiguration().ServiceBrokerExtUrl
 }
 
 func GetskipSslFlag() bool {
-	return getConfiguration().SkipSslFlag
-}
-
-func getConfiguration() Configuration {
-	var path string
-	var CF_HOME string = os.Getenv("CF_HOME")
-	if CF_HOME == "" {
-		CF_HOME = helper.GetHomeDir()
-	}
-	path = CF_HOME + "/.cf/conf.json"
-	file, _ := os.Open(path)
-	decoder := json.NewDecoder(file)
-	configuration := Configuration{}
-	err := decoder.Decode(&configuration)
-	if err != nil {
-		fmt.Println("error:", err)
-	}
-	return configuration
+	return helper.SkipSslVerification()
 }
 
 func GetHttpClient() *http.Client {
-	//Skip ssl verification.
-
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: GetskipSslFlag()},
-			Proxy:           http.ProxyFromEnvironment,
-		},
-		Timeout: time.Duration(constants.RequestTimeout) * time.Second,
-	}
-	return client
+	return helper.NewHttpClient()
 }
 
 func GetResponse(client *http.Client, req *http.Request) *http.Response {
@@ -92,6 +58,16 @@ func GetResponse(client *http.Client, req *http.Request) *http.Response {
 	return resp
 }
 
+// refreshAccessToken makes the cf CLI refresh the access token in config.json if it is expired, for commands which do not
+// call the cloud controller before the broker.
+func refreshAccessToken(cliConnection plugin.CliConnection) {
+	//TODO: This is a workaround to get refreshed jwt token if it is expired, we need to see if this is correct way??
+	var cmd string = "/v2/service_instances"
+	if _, err := cliConnection.CliCommandWithoutTerminalOutput("curl", cmd); err != nil {
+		errors.CfCliPluginError(cmd)
+	}
+}
+
 func (c *BackupCommand) BackupInfo(cliConnection plugin.CliConnection, backupId string) {
 
 	fmt.Println("Retrieving information about backup id: ", AddColor(backupId, constants.Cyan), "...")
@@ -104,30 +80,18 @@ func (c *BackupCommand) BackupInfo(cliConnection plugin.CliConnection, backupId
 
 	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
 
-	//TODO: This is a workaround to get refreshed jwt token if it is expired, we need to see if this is correct way??
-	var cmd string = "/v2/service_instances"
-	output, err := cliConnection.CliCommandWithoutTerminalOutput("curl", cmd)
-	if output != nil {
-		output = nil
-	}
-	if err != nil {
-		errors.CfCliPluginError(cmd)
-	}
-
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
+	refreshAccessToken(cliConnection)
 
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	var url string = apiEndpoint + extUrl + "/backups/" + backupId + "?space_guid=" + userSpaceGuid
+	var url string = apiEndpoint + "/backups/" + backupId + "?space_guid=" + userSpaceGuid
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, _ := http.NewRequest("GET", url, nil)
 
 	var resp *http.Response = GetResponse(client, req)
 	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
+	body, _ := ioutil.ReadAll(resp.Body)
 
 	table := tablewriter.NewWriter(os.Stdout)
 	table.SetBorder(false)
@@ -171,6 +135,8 @@ func (c *BackupCommand) BackupInfo(cliConnection plugin.CliConnection, backupId
 		} else {
 			table.Append([]string{"finished_at", "null"})
 		}
+		finishedAt, _ := response["finished_at"].(string)
+		table.Append([]string{"duration", helper.FormatDuration(response["started_at"].(string), finishedAt)})
 		table.Render()
 	}
 
@@ -183,8 +149,10 @@ func (c *BackupCommand) BackupInfo(cliConnection plugin.CliConnection, backupId
 
 }
 
-func (c *BackupCommand) ListBackupsByDeletedInstanceName(cliConnection plugin.CliConnection, serviceInstanceName string) {
-	fmt.Println("Getting the list of  backups in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "/ service instance", AddColor(serviceInstanceName, constants.Cyan), "...")
+func (c *BackupCommand) ListBackupsByDeletedInstanceName(cliConnection plugin.CliConnection, serviceInstanceName string, pick string, jsonOutput bool) {
+	if !jsonOutput {
+		fmt.Println("Getting the list of  backups in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "/ service instance", AddColor(serviceInstanceName, constants.Cyan), "...")
+	}
 
 	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
 		errors.NoAccessTokenError("Access Token")
@@ -193,27 +161,51 @@ func (c *BackupCommand) ListBackupsByDeletedInstanceName(cliConnection plugin.Cl
 	client := GetHttpClient()
 
 	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	var guids []string = findDeletedInstanceGuids(cliConnection, userSpaceGuid, serviceInstanceName)
 	var guid string
-	var guidMap map[string]string = guidTranslator.FindDeletedInstanceGuid(cliConnection, serviceInstanceName, nil, "")
-	if len(guidMap) > 1 {
-		fmt.Println(AddColor("FAILED", constants.Red))
-		fmt.Println("" + serviceInstanceName + " maps to multiple instance GUIDs, please use 'cf instance-events --delete' to list all instance delete events, get required instance guid from the list and then use 'cf list-backup --guid GUID' to fetch backups list.")
-		fmt.Println("Enter 'cf backup' to check the list of commands and their usage.")
-		os.Exit(1)
-	} else {
-		for k, _ := range guidMap {
-			guid = k
-			guid = strings.Trim(guid, ",")
-			guid = strings.Trim(guid, "\"")
-		}
+	if len(guids) == 1 {
+		guid = guids[0]
 	}
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
 
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
+	var candidates []DeletedInstanceCandidate
+	if len(guids) > 1 || jsonOutput {
+		records, err := GetBackupRecords(client, userSpaceGuid, "")
+		if err != nil {
+			fmt.Println(AddColor("FAILED", constants.Red))
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		candidates = deletedInstanceCandidates(cliConnection, userSpaceGuid, guids, records)
+		if len(candidates) > 1 && !(jsonOutput && pick == "") {
+			guid = pickDeletedInstance(serviceInstanceName, candidates, pick)
+		}
+
+		if jsonOutput {
+			var backups []BackupRecord = []BackupRecord{}
+			for _, record := range records {
+				if guid != "" && record.InstanceGuid == guid {
+					backups = append(backups, record)
+				}
+			}
+			output, _ := json.MarshalIndent(map[string]interface{}{"instance_name": serviceInstanceName, "candidates": candidates, "instance_guid": guid, "backups": backups}, "", "  ")
+			fmt.Println(string(output))
+			if guid == "" {
+				os.Exit(1)
+			}
+			return
+		}
 
-	req, err := http.NewRequest("GET", apiEndpoint+extUrl+"/backups"+"?space_guid="+userSpaceGuid+"&instance_id="+guid, nil)
+		if guid == "" {
+			fmt.Println(AddColor("FAILED", constants.Red))
+			fmt.Println("" + serviceInstanceName + " maps to multiple instance GUIDs, please use '--pick newest' or '--pick oldest' to choose one of them, or use 'cf instance-events --delete' to list all instance delete events, get required instance guid from the list and then use 'cf list-backup --guid GUID' to fetch backups list.")
+			fmt.Println("Enter 'cf backup' to check the list of commands and their usage.")
+			os.Exit(1)
+		}
+		fmt.Println("Using instance GUID", AddColor(guid, constants.Cyan), "...")
+	}
+	var apiEndpoint string = helper.GetBrokerApiUrl()
+
+	req, err := http.NewRequest("GET", apiEndpoint+"/backups"+"?space_guid="+userSpaceGuid+"&instance_id="+guid, nil)
 	errors.ErrorIsNil(err)
 	var resp *http.Response = GetResponse(client, req)
 
@@ -235,7 +227,7 @@ func (c *BackupCommand) ListBackupsByDeletedInstanceName(cliConnection plugin.Cl
 		fmt.Println(AddColor("OK", constants.Green))
 		var flag bool
 
-		table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White)})
+		table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White), AddColor("duration", constants.White)})
 
 		var response []interface{}
 		if err := json.Unmarshal(body, &response); err != nil {
@@ -243,7 +235,7 @@ func (c *BackupCommand) ListBackupsByDeletedInstanceName(cliConnection plugin.Cl
 			fmt.Println(string(body[:]))
 		}
 
-		var no_of_columns int = 6
+		var no_of_columns int = 7
 		var field = make([]string, no_of_columns)
 
 		for backup := range response {
@@ -258,6 +250,7 @@ func (c *BackupCommand) ListBackupsByDeletedInstanceName(cliConnection plugin.Cl
 				if flag == false {
 					field[5] = "null"
 				}
+				field[6] = helper.FormatDuration(field[4], field[5])
 				table.Append(field)
 			}
 		}
@@ -303,13 +296,9 @@ func (c *BackupCommand) ListBackupsByInstance(cliConnection plugin.CliConnection
 			errors.IncorrectServiceType(serviceInstanceName, serviceName)
 		}
 	}
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
-
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	req, err := http.NewRequest("GET", apiEndpoint+extUrl+"/backups"+"?space_guid="+userSpaceGuid+"&instance_id="+guid, nil)
+	req, err := http.NewRequest("GET", apiEndpoint+"/backups"+"?space_guid="+userSpaceGuid+"&instance_id="+guid, nil)
 	errors.ErrorIsNil(err)
 
 	var resp *http.Response = GetResponse(client, req)
@@ -342,9 +331,9 @@ func (c *BackupCommand) ListBackupsByInstance(cliConnection plugin.CliConnection
 
 		var flag bool
 
-		table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White)})
+		table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White), AddColor("duration", constants.White)})
 
-		var no_of_columns int = 6
+		var no_of_columns int = 7
 		var field = make([]string, no_of_columns)
 		for backup := range response {
 			field[1] = (response[backup].(map[string]interface{}))["username"].(string)
@@ -357,6 +346,7 @@ func (c *BackupCommand) ListBackupsByInstance(cliConnection plugin.CliConnection
 			if flag == false {
 				field[5] = "null"
 			}
+			field[6] = helper.FormatDuration(field[4], field[5])
 			table.Append(field)
 		}
 
@@ -392,13 +382,9 @@ func (c *BackupCommand) ListBackups(cliConnection plugin.CliConnection, noInstan
 
 	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
 
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
-
-	req, err := http.NewRequest("GET", apiEndpoint+extUrl+"/backups"+"?space_guid="+userSpaceGuid, nil)
+	req, err := http.NewRequest("GET", apiEndpoint+"/backups"+"?space_guid="+userSpaceGuid, nil)
 	errors.ErrorIsNil(err)
 
 	var resp *http.Response = GetResponse(client, req)
@@ -426,13 +412,13 @@ func (c *BackupCommand) ListBackups(cliConnection plugin.CliConnection, noInstan
 			fmt.Println("Invalid response for the request ", err)
 		}
 
-		var no_of_columns int = 8
+		var no_of_columns int = 9
 		var field = make([]string, no_of_columns)
 
 		if noInstanceNames == true {
-			table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("instance_guid", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White), AddColor(" ", constants.White)})
+			table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("instance_guid", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White), AddColor("duration", constants.White), AddColor(" ", constants.White)})
 		} else {
-			table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("instance_name", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White), AddColor(" ", constants.White)})
+			table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("instance_name", constants.White), AddColor("username", constants.White), AddColor("type", constants.White), AddColor("trigger", constants.White), AddColor("started_at", constants.White), AddColor("finished_at", constants.White), AddColor("duration", constants.White), AddColor(" ", constants.White)})
 		}
 
 		for backup := range response {
@@ -444,9 +430,9 @@ func (c *BackupCommand) ListBackups(cliConnection plugin.CliConnection, noInstan
 				var InstanceName = guidTranslator.FindInstanceName(cliConnection, instance_guid, nil)
 				field[1] = strings.Trim(InstanceName, "\"")
 				if InstanceName == "" {
-					field[7] = "Status: Instance already deleted"
+					field[8] = "Status: Instance already deleted"
 				} else {
-					field[7] = ""
+					field[8] = ""
 				}
 			}
 			field[2] = (response[backup].(map[string]interface{}))["username"].(string)
@@ -461,6 +447,7 @@ func (c *BackupCommand) ListBackups(cliConnection plugin.CliConnection, noInstan
 			} else {
 				field[6] = (response[backup].(map[string]interface{}))["finished_at"].(string)
 			}
+			field[7] = helper.FormatDuration(field[5], field[6])
 			table.Append(field)
 		}
 	}
@@ -497,13 +484,9 @@ func (c *BackupCommand) DeleteBackup(cliConnection plugin.CliConnection, backupI
 		errors.CfCliPluginError(cmd)
 	}
 
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
-
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	var url string = apiEndpoint + extUrl + "/backups/" + backupId + "?space_guid=" + userSpaceGuid
+	var url string = apiEndpoint + "/backups/" + backupId + "?space_guid=" + userSpaceGuid
 	req, err := http.NewRequest("DELETE", url, nil)
 
 	var resp *http.Response = GetResponse(client, req)
@@ -522,8 +505,12 @@ func (c *BackupCommand) DeleteBackup(cliConnection plugin.CliConnection, backupI
 	}
 }
 
-func (c *BackupCommand) AbortBackup(cliConnection plugin.CliConnection, serviceInstanceName string) {
-	fmt.Println("Aborting backup for ", AddColor(serviceInstanceName, constants.Cyan), "...")
+func (c *BackupCommand) AbortBackup(cliConnection plugin.CliConnection, serviceInstanceName string, instanceGuid string, inputGuidBool bool) {
+	if inputGuidBool == true {
+		fmt.Println("Aborting backup for ", AddColor(instanceGuid, constants.Cyan), "...")
+	} else {
+		fmt.Println("Aborting backup for ", AddColor(serviceInstanceName, constants.Cyan), "...")
+	}
 
 	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
 		errors.NoAccessTokenError("Access Token")
@@ -531,17 +518,11 @@ func (c *BackupCommand) AbortBackup(cliConnection plugin.CliConnection, serviceI
 
 	client := GetHttpClient()
 
-	var guid string = guidTranslator.FindInstanceGuid(cliConnection, serviceInstanceName, nil, "")
-	guid = strings.TrimRight(guid, ",")
-	guid = strings.Trim(guid, "\"")
-
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
+	guid, _, _ := guidTranslator.ResolveInstance(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	var url string = apiEndpoint + extUrl + "/service_instances/" + guid + "/backup"
+	var url string = apiEndpoint + "/service_instances/" + guid + "/backup"
 	req, err := http.NewRequest("DELETE", url, nil)
 
 	var resp *http.Response = GetResponse(client, req)
@@ -567,8 +548,14 @@ func (c *BackupCommand) AbortBackup(cliConnection plugin.CliConnection, serviceI
 	errors.ErrorIsNil(err)
 }
 
-func (c *BackupCommand) StartBackup(cliConnection plugin.CliConnection, serviceInstanceName string) {
-	fmt.Println("Triggering backup for ", AddColor(serviceInstanceName, constants.Cyan), "...")
+// StartBackup triggers an online backup of the instance. With waitForIdle, it first waits up to idleTimeout until no other
+// operation is in progress on the instance.
+func (c *BackupCommand) StartBackup(cliConnection plugin.CliConnection, serviceInstanceName string, instanceGuid string, inputGuidBool bool, waitForIdle bool, idleTimeout time.Duration) {
+	if inputGuidBool == true {
+		fmt.Println("Triggering backup for ", AddColor(instanceGuid, constants.Cyan), "...")
+	} else {
+		fmt.Println("Triggering backup for ", AddColor(serviceInstanceName, constants.Cyan), "...")
+	}
 
 	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
 		errors.NoAccessTokenError("Access Token")
@@ -579,26 +566,32 @@ func (c *BackupCommand) StartBackup(cliConnection plugin.CliConnection, serviceI
 	var jsonprep string = `{"type": "online"}`
 
 	var jsonStr = []byte(jsonprep)
-	var req_body = bytes.NewBuffer(jsonStr)
 
-	var guid string = guidTranslator.FindInstanceGuid(cliConnection, serviceInstanceName, nil, "")
-	guid = strings.TrimRight(guid, ",")
-	guid = strings.Trim(guid, "\"")
+	guid, _, userSpaceGuid := guidTranslator.ResolveInstance(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
+	var url string = apiEndpoint + "/service_instances/" + guid + "/backup"
 
-	var url string = apiEndpoint + extUrl + "/service_instances/" + guid + "/backup"
-
-	req, err := http.NewRequest("POST", url, req_body)
+	if waitForIdle {
+		if err := WaitForIdle(cliConnection, client, guid, userSpaceGuid, idleTimeout); err != nil {
+			fmt.Println(AddColor("FAILED", constants.Red))
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
 
-	var resp *http.Response = GetResponse(client, req)
+	//A failed attempt is only retried if it did not start a backup after all.
+	resp, body, started, err := helper.StartBrokerOperation(client, url, jsonStr, findStartedBackup(client, userSpaceGuid, guid))
+	if started != nil {
+		fmt.Println(AddColor("OK", constants.Green))
+		fmt.Println("The backup was started by a previous attempt.")
+		fmt.Println("BACKUP_ID is", AddColor(started.Guid, constants.Cyan))
+		fmt.Println("Check the state of the backup using cf backup BACKUP_ID command.")
+		return
+	}
+	errors.ErrorIsNil(err)
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
 	if resp.Status != "202 Accepted" {
 		fmt.Println(AddColor("FAILED", constants.Red))
 		var message string = string(body)
@@ -0,0 +1,111 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/cloudfoundry/cli/plugin"
+	"github.com/mattn/go-isatty"
+)
+
+// DeletedInstanceCandidate is one of the instance guids a deleted instance name maps to.
+type DeletedInstanceCandidate struct {
+	InstanceGuid string `json:"instance_guid"`
+	DeletedAt    string `json:"deleted_at"`
+	DeletedBy    string `json:"deleted_by"`
+	BackupCount  int    `json:"backup_count"`
+}
+
+// deletedInstanceCandidates enriches the given instance guids with their delete event and backup count, oldest deletion first.
+func deletedInstanceCandidates(cliConnection plugin.CliConnection, userSpaceGuid string, guids []string, records []BackupRecord) []DeletedInstanceCandidate {
+	deletedInstances, _ := guidTranslator.FindDeletedInstanceEvents(cliConnection, userSpaceGuid, time.Time{})
+	return toDeletedInstanceCandidates(guids, deletedInstances, records)
+}
+
+// toDeletedInstanceCandidates is deletedInstanceCandidates with the delete events already fetched. Candidates without
+// a delete event, e.g. because it has expired, count as deleted first.
+func toDeletedInstanceCandidates(guids []string, deletedInstances map[string]guidTranslator.InstanceEvent, records []BackupRecord) []DeletedInstanceCandidate {
+	summaries := SummarizeBackups(records)
+
+	var candidates []DeletedInstanceCandidate
+	for _, guid := range guids {
+		candidate := DeletedInstanceCandidate{InstanceGuid: guid}
+		if event, flag := deletedInstances[guid]; flag {
+			candidate.DeletedAt = event.Timestamp
+			candidate.DeletedBy = event.Actor
+		}
+		if summary, flag := summaries[guid]; flag {
+			candidate.BackupCount = summary.Count
+		}
+		candidates = append(candidates, candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].DeletedAt < candidates[j].DeletedAt
+	})
+	return candidates
+}
+
+// pickDeletedInstance resolves the candidates to a single instance guid. With pick set to "newest" or "oldest" the choice is made
+// without asking, otherwise the user is asked when attached to a terminal. An empty string is returned if no choice could be made.
+func pickDeletedInstance(serviceInstanceName string, candidates []DeletedInstanceCandidate, pick string) string {
+	if pick == "oldest" {
+		return candidates[0].InstanceGuid
+	}
+	if pick == "newest" {
+		return candidates[len(candidates)-1].InstanceGuid
+	}
+	if !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return ""
+	}
+
+	fmt.Println(serviceInstanceName, "maps to multiple deleted instances:")
+	table := NewTable()
+	table.SetHeader([]string{AddColor("#", constants.White), AddColor("instance_guid", constants.White), AddColor("deleted_at", constants.White), AddColor("deleted_by", constants.White), AddColor("backups", constants.White)})
+	for index, candidate := range candidates {
+		table.Append([]string{strconv.Itoa(index + 1), AddColor(candidate.InstanceGuid, constants.Cyan), candidate.DeletedAt, candidate.DeletedBy, strconv.Itoa(candidate.BackupCount)})
+	}
+	table.Render()
+
+	for {
+		fmt.Print("Select an instance (1-" + strconv.Itoa(len(candidates)) + "): ")
+		var userChoice string
+		if _, err := fmt.Scanln(&userChoice); err != nil && userChoice == "" {
+			return ""
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(userChoice))
+		if err == nil && choice >= 1 && choice <= len(candidates) {
+			return candidates[choice-1].InstanceGuid
+		}
+		fmt.Println("Invalid selection.")
+	}
+}
+
+// findDeletedInstanceGuids returns the guids of the deleted instances of the space which had the given name at any time,
+// so that an instance renamed before its deletion is found by each of its names.
+func findDeletedInstanceGuids(cliConnection plugin.CliConnection, userSpaceGuid string, serviceInstanceName string) []string {
+	guids, err := guidTranslator.FindDeletedInstanceGuidsByName(cliConnection, userSpaceGuid, serviceInstanceName)
+	if err != nil {
+		errors.CfCliPluginError("/v2/events")
+	}
+	if len(guids) == 0 {
+		errors.InstanceGuidNotFound(serviceInstanceName)
+	}
+	return guids
+}
+
+// ResolveDeletedInstance returns the guid of the deleted instance with the given name. If the name maps to several
+// deleted instances the choice is made as described for pickDeletedInstance. An empty string is returned if no choice could be made.
+func ResolveDeletedInstance(cliConnection plugin.CliConnection, userSpaceGuid string, serviceInstanceName string, records []BackupRecord, pick string) string {
+	var guids []string = findDeletedInstanceGuids(cliConnection, userSpaceGuid, serviceInstanceName)
+	if len(guids) == 1 {
+		return guids[0]
+	}
+	return pickDeletedInstance(serviceInstanceName, deletedInstanceCandidates(cliConnection, userSpaceGuid, guids, records), pick)
+}
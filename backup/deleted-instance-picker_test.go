@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("deleted instance picker", func() {
+	records := []BackupRecord{
+		{BackupGuid: "b1", InstanceGuid: "i1", State: "succeeded", StartedAt: "2018-11-01T00:00:00Z"},
+		{BackupGuid: "b2", InstanceGuid: "i1", State: "succeeded", StartedAt: "2018-11-02T00:00:00Z"},
+		{BackupGuid: "b3", InstanceGuid: "i2", State: "succeeded", StartedAt: "2018-11-03T00:00:00Z"},
+	}
+	deleted := map[string]guidTranslator.InstanceEvent{
+		"i1": {InstanceGuid: "i1", Actor: "admin", Timestamp: "2018-11-20T00:00:00Z"},
+		"i2": {InstanceGuid: "i2", Actor: "bob", Timestamp: "2018-11-10T00:00:00Z"},
+	}
+	candidates := toDeletedInstanceCandidates([]string{"i1", "i2", "i3"}, deleted, records)
+
+	It("The candidates should be ordered by deletion, oldest first", func() {
+		Expect(candidates).To(Equal([]DeletedInstanceCandidate{
+			{InstanceGuid: "i3"},
+			{InstanceGuid: "i2", DeletedAt: "2018-11-10T00:00:00Z", DeletedBy: "bob", BackupCount: 1},
+			{InstanceGuid: "i1", DeletedAt: "2018-11-20T00:00:00Z", DeletedBy: "admin", BackupCount: 2},
+		}))
+	})
+	It("--pick should choose the newest or the oldest deletion", func() {
+		Expect(pickDeletedInstance("blueprint", candidates, "newest")).To(Equal("i1"))
+		Expect(pickDeletedInstance("blueprint", candidates, "oldest")).To(Equal("i3"))
+	})
+	It("Without --pick and without a terminal no choice should be made", func() {
+		Expect(pickDeletedInstance("blueprint", candidates, "")).To(BeEmpty())
+	})
+})
@@ -0,0 +1,79 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// ListDeletedInstances joins the instance delete events of the space with the backups listing and shows the deleted instances
+// which still have backups. If since is not the zero time, only instances deleted after it are shown.
+func (c *BackupCommand) ListDeletedInstances(cliConnection plugin.CliConnection, since time.Time) {
+	fmt.Println("Getting the list of deleted instances with backups in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	client := GetHttpClient()
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+
+	deletedInstances, err := guidTranslator.FindDeletedInstanceEvents(cliConnection, userSpaceGuid, since)
+	if err != nil {
+		errors.CfCliPluginError("/v2/events")
+	}
+
+	records, err := GetBackupRecords(client, userSpaceGuid, "")
+	if err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	summaries := SummarizeBackups(records)
+
+	var events []guidTranslator.InstanceEvent
+	for instanceGuid, event := range deletedInstances {
+		if _, flag := summaries[instanceGuid]; flag {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp > events[j].Timestamp
+	})
+
+	fmt.Println(AddColor("OK", constants.Green))
+
+	serviceNames := make(map[string]string)
+	planNames := make(map[string]string)
+
+	table := NewTable()
+	table.SetHeader([]string{AddColor("instance_name", constants.White), AddColor("instance_guid", constants.White), AddColor("deleted_at", constants.White), AddColor("deleted_by", constants.White), AddColor("service", constants.White), AddColor("plan", constants.White), AddColor("backups", constants.White), AddColor("newest_backup", constants.White)})
+	for _, event := range events {
+		summary := summaries[event.InstanceGuid]
+		serviceId := summary.Newest.ServiceId
+		if _, flag := serviceNames[serviceId]; !flag {
+			serviceNames[serviceId] = strings.Trim(guidTranslator.FindServiceName(cliConnection, serviceId, nil), "\"")
+		}
+		planId := summary.Newest.PlanId
+		if _, flag := planNames[planId]; !flag {
+			planNames[planId] = strings.Trim(guidTranslator.FindPlanName(cliConnection, planId, nil), "\"")
+		}
+		table.Append([]string{event.InstanceName, AddColor(event.InstanceGuid, constants.Cyan), event.Timestamp, event.Actor, serviceNames[serviceId], planNames[planId], strconv.Itoa(summary.Count), summary.Newest.BackupGuid + " (" + summary.Newest.StartedAt + ")"})
+	}
+	table.Render()
+
+	if len(events) > 0 {
+		fmt.Println()
+		fmt.Println("Use 'cf list-backup --guid INSTANCE_GUID' to list the backups of a deleted instance.")
+	}
+}
@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// OrphanedInstance describes a deleted service instance which still has backups.
+type OrphanedInstance struct {
+	InstanceGuid     string `json:"instance_guid"`
+	LastKnownName    string `json:"last_known_name"`
+	DeletedAt        string `json:"deleted_at"`
+	DeletedBy        string `json:"deleted_by"`
+	BackupCount      int    `json:"backup_count"`
+	NewestBackupGuid string `json:"newest_backup_guid"`
+	NewestBackupAt   string `json:"newest_backup_at"`
+}
+
+// ClassifyOrphans returns the instances with backups which no longer exist, newest deletion first, and their backups.
+// The name and the deletion of an orphan are taken from its delete event, if the cloud controller still has it.
+func ClassifyOrphans(records []BackupRecord, existingInstances map[string]bool, deletedInstances map[string]guidTranslator.InstanceEvent) ([]OrphanedInstance, []BackupRecord) {
+	var orphans []OrphanedInstance
+	var orphanedRecords []BackupRecord
+	for instanceGuid, summary := range SummarizeBackups(records) {
+		if existingInstances[instanceGuid] {
+			continue
+		}
+		orphan := OrphanedInstance{
+			InstanceGuid:     instanceGuid,
+			BackupCount:      summary.Count,
+			NewestBackupGuid: summary.Newest.BackupGuid,
+			NewestBackupAt:   summary.Newest.StartedAt,
+		}
+		if event, flag := deletedInstances[instanceGuid]; flag {
+			orphan.LastKnownName = event.InstanceName
+			orphan.DeletedAt = event.Timestamp
+			orphan.DeletedBy = event.Actor
+		}
+		orphans = append(orphans, orphan)
+		orphanedRecords = append(orphanedRecords, summary.Backups...)
+	}
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].DeletedAt > orphans[j].DeletedAt
+	})
+	return orphans, orphanedRecords
+}
+
+func (c *BackupCommand) ListOrphanedBackups(cliConnection plugin.CliConnection, jsonOutput bool, pruneOlderThan time.Duration) {
+	if !jsonOutput {
+		fmt.Println("Getting the list of orphaned backups in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
+	}
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	client := GetHttpClient()
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+
+	existingInstances, err := guidTranslator.FindExistingInstanceGuids(cliConnection)
+	if err != nil {
+		errors.CfCliPluginError("/v2/service_instances")
+	}
+	deletedInstances, err := guidTranslator.FindDeletedInstanceEvents(cliConnection, userSpaceGuid, time.Time{})
+	if err != nil {
+		errors.CfCliPluginError("/v2/events")
+	}
+
+	records, err := GetBackupRecords(client, userSpaceGuid, "")
+	if err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	orphans, orphanedRecords := ClassifyOrphans(records, existingInstances, deletedInstances)
+
+	if jsonOutput {
+		if orphans == nil {
+			orphans = []OrphanedInstance{}
+		}
+		output, _ := json.MarshalIndent(orphans, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	fmt.Println(AddColor("OK", constants.Green))
+	table := NewTable()
+	table.SetHeader([]string{AddColor("instance_guid", constants.White), AddColor("last_known_name", constants.White), AddColor("deleted_at", constants.White), AddColor("backups", constants.White), AddColor("newest_backup", constants.White)})
+	for _, orphan := range orphans {
+		var name string = orphan.LastKnownName
+		var deletedAt string = orphan.DeletedAt
+		if name == "" {
+			name = "unknown"
+			deletedAt = "unknown"
+		}
+		table.Append([]string{AddColor(orphan.InstanceGuid, constants.Cyan), name, deletedAt, strconv.Itoa(orphan.BackupCount), orphan.NewestBackupAt})
+	}
+	table.Render()
+
+	if pruneOlderThan <= 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Computing orphaned backups older than", AddColor(pruneOlderThan.String(), constants.Cyan), "...")
+	decisions := PlanPrune(orphanedRecords, 0, pruneOlderThan, false, time.Now())
+	toDelete := printPrunePlan(cliConnection, decisions)
+	if toDelete == 0 {
+		return
+	}
+
+	fmt.Println("Are you sure you want to delete " + strconv.Itoa(toDelete) + " backup(s)? (y/n)")
+	var userChoice string
+	fmt.Scanln(&userChoice)
+	if userChoice != "y" {
+		os.Exit(7)
+	}
+	deletePlannedBackups(client, userSpaceGuid, decisions, toDelete)
+}
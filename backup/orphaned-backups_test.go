@@ -0,0 +1,50 @@
+package backup
+
+import (
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("orphaned backups", func() {
+	records := []BackupRecord{
+		{BackupGuid: "b1", InstanceGuid: "i1", State: "succeeded", StartedAt: "2018-11-01T00:00:00Z"},
+		{BackupGuid: "b2", InstanceGuid: "i2", State: "succeeded", StartedAt: "2018-11-02T00:00:00Z"},
+		{BackupGuid: "b3", InstanceGuid: "i2", State: "failed", StartedAt: "2018-11-03T00:00:00Z"},
+		{BackupGuid: "b4", InstanceGuid: "i3", State: "succeeded", StartedAt: "2018-11-04T00:00:00Z"},
+		{BackupGuid: "b5", InstanceGuid: "i4", State: "succeeded", StartedAt: "2018-11-05T00:00:00Z"},
+	}
+	existing := map[string]bool{"i1": true}
+	deleted := map[string]guidTranslator.InstanceEvent{
+		"i2": {InstanceGuid: "i2", InstanceName: "old-blueprint", Actor: "admin", Timestamp: "2018-11-10T00:00:00Z"},
+		"i3": {InstanceGuid: "i3", InstanceName: "older-blueprint", Actor: "bob", Timestamp: "2018-11-08T00:00:00Z"},
+	}
+
+	It("Only instances which no longer exist should be orphans, newest deletion first", func() {
+		orphans, orphanedRecords := ClassifyOrphans(records, existing, deleted)
+		var guids []string
+		for _, orphan := range orphans {
+			guids = append(guids, orphan.InstanceGuid)
+		}
+		Expect(guids).To(Equal([]string{"i2", "i3", "i4"}))
+		Expect(orphanedRecords).To(HaveLen(4))
+	})
+	It("The delete event should name an orphan and its backups should be summarized", func() {
+		orphans, _ := ClassifyOrphans(records, existing, deleted)
+		Expect(orphans[0]).To(Equal(OrphanedInstance{
+			InstanceGuid:     "i2",
+			LastKnownName:    "old-blueprint",
+			DeletedAt:        "2018-11-10T00:00:00Z",
+			DeletedBy:        "admin",
+			BackupCount:      2,
+			NewestBackupGuid: "b3",
+			NewestBackupAt:   "2018-11-03T00:00:00Z",
+		}))
+	})
+	It("An orphan whose delete event has expired should stay unnamed", func() {
+		orphans, _ := ClassifyOrphans(records, existing, deleted)
+		Expect(orphans[2].InstanceGuid).To(Equal("i4"))
+		Expect(orphans[2].LastKnownName).To(BeEmpty())
+		Expect(orphans[2].DeletedAt).To(BeEmpty())
+	})
+})
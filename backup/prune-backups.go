@@ -0,0 +1,198 @@
+package backup
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// PruneDecision tells whether a backup is deleted by a prune run and why.
+type PruneDecision struct {
+	Backup BackupRecord
+	Delete bool
+	Reason string
+}
+
+type pruneResult struct {
+	BackupGuid string
+	Err        error
+}
+
+// PlanPrune computes the deletion set for the given backups. Exactly one of keepLast and olderThan is expected to be set.
+// The newest successful backup of every instance and backups that are still in progress are always kept. keepLast counts
+// the successful backups only, so that a run of failed backups does not push the successful ones out of the kept set.
+// With onlyOnDemand it counts the successful on-demand backups only, as the scheduled ones are never deleted.
+func PlanPrune(records []BackupRecord, keepLast int, olderThan time.Duration, onlyOnDemand bool, now time.Time) []PruneDecision {
+	SortBackupRecords(records)
+
+	var decisions []PruneDecision
+	keptNewestSucceeded := make(map[string]bool)
+	succeeded := make(map[string]int)
+
+	for _, record := range records {
+		decision := PruneDecision{Backup: record}
+		position := succeeded[record.InstanceGuid]
+		if record.IsSucceeded() && (!onlyOnDemand || record.Trigger == constants.BackupTriggerOnDemand) {
+			succeeded[record.InstanceGuid] = position + 1
+		}
+
+		if record.IsSucceeded() && !keptNewestSucceeded[record.InstanceGuid] {
+			keptNewestSucceeded[record.InstanceGuid] = true
+			decision.Reason = "newest successful backup"
+		} else if record.IsInProgress() {
+			decision.Reason = "in progress"
+		} else if onlyOnDemand && record.Trigger != constants.BackupTriggerOnDemand {
+			decision.Reason = record.Trigger + " backup"
+		} else if keepLast > 0 && record.IsSucceeded() && position < keepLast {
+			decision.Reason = "within last " + strconv.Itoa(keepLast)
+		} else if olderThan > 0 && record.StartTime().After(now.Add(-olderThan)) {
+			decision.Reason = "newer than " + olderThan.String()
+		} else {
+			decision.Delete = true
+		}
+		decisions = append(decisions, decision)
+	}
+	return decisions
+}
+
+// executePrunePlan deletes the backups marked for deletion, running at most constants.MaxConcurrentRequests deletions at a time.
+func executePrunePlan(client *http.Client, userSpaceGuid string, decisions []PruneDecision) []pruneResult {
+	var results []pruneResult
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, constants.MaxConcurrentRequests)
+
+	for _, decision := range decisions {
+		if !decision.Delete {
+			continue
+		}
+		wg.Add(1)
+		go func(backupGuid string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			err := DeleteBackupRecord(client, userSpaceGuid, backupGuid)
+			<-semaphore
+
+			mutex.Lock()
+			results = append(results, pruneResult{BackupGuid: backupGuid, Err: err})
+			mutex.Unlock()
+		}(decision.Backup.BackupGuid)
+	}
+	wg.Wait()
+	return results
+}
+
+func (c *BackupCommand) PruneBackups(cliConnection plugin.CliConnection, serviceInstanceName string, keepLast int, olderThan time.Duration, onlyOnDemand bool, confirm bool) {
+	fmt.Println("Computing backups to prune in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	client := GetHttpClient()
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	var guid string
+	if serviceInstanceName != "" {
+		guid = guidTranslator.FindInstanceGuid(cliConnection, serviceInstanceName, nil, "")
+		serviceName := guidTranslator.ServiceNameFromInstance(cliConnection, serviceInstanceName)
+		if !guidTranslator.IsServiceNameValid(serviceName) {
+			errors.IncorrectServiceType(serviceInstanceName, serviceName)
+		}
+	} else {
+		refreshAccessToken(cliConnection)
+	}
+
+	records, err := GetBackupRecords(client, userSpaceGuid, guid)
+	if err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(AddColor("OK", constants.Green))
+
+	decisions := PlanPrune(records, keepLast, olderThan, onlyOnDemand, time.Now())
+	toDelete := printPrunePlan(cliConnection, decisions)
+
+	if !confirm {
+		fmt.Println("This was a dry run. Re-run the command with --confirm to delete the backups.")
+		return
+	}
+	deletePlannedBackups(client, userSpaceGuid, decisions, toDelete)
+}
+
+// printPrunePlan shows the planned action for every backup and returns the number of backups to delete.
+func printPrunePlan(cliConnection plugin.CliConnection, decisions []PruneDecision) int {
+	instanceNames := make(map[string]string)
+
+	table := NewTable()
+	table.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("instance_name", constants.White), AddColor("trigger", constants.White), AddColor("state", constants.White), AddColor("started_at", constants.White), AddColor("action", constants.White)})
+
+	var toDelete int
+	for _, decision := range decisions {
+		var action string = "keep (" + decision.Reason + ")"
+		if decision.Delete {
+			action = AddColor("delete", constants.Red)
+			toDelete++
+		}
+		table.Append([]string{AddColor(decision.Backup.BackupGuid, constants.Cyan), instanceNameOf(cliConnection, decision.Backup.InstanceGuid, instanceNames), decision.Backup.Trigger, decision.Backup.State, decision.Backup.StartedAt, action})
+	}
+	table.Render()
+	fmt.Println()
+	fmt.Println(strconv.Itoa(toDelete), "backup(s) will be deleted,", strconv.Itoa(len(decisions)-toDelete), "backup(s) will be kept.")
+	return toDelete
+}
+
+// deletePlannedBackups executes the plan and prints the result of every deletion.
+func deletePlannedBackups(client *http.Client, userSpaceGuid string, decisions []PruneDecision, toDelete int) {
+	if toDelete == 0 {
+		return
+	}
+
+	fmt.Println("Deleting", strconv.Itoa(toDelete), "backup(s) ...")
+	results := executePrunePlan(client, userSpaceGuid, decisions)
+
+	resultTable := NewTable()
+	resultTable.SetHeader([]string{AddColor("backup_guid", constants.White), AddColor("result", constants.White)})
+	var failed int
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			resultTable.Append([]string{AddColor(result.BackupGuid, constants.Cyan), AddColor("FAILED", constants.Red) + " " + result.Err.Error()})
+		} else {
+			resultTable.Append([]string{AddColor(result.BackupGuid, constants.Cyan), AddColor("deleted", constants.Green)})
+		}
+	}
+	resultTable.Render()
+
+	if failed > 0 {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(strconv.Itoa(len(results)-failed), "backup(s) deleted,", strconv.Itoa(failed), "deletion(s) failed.")
+		os.Exit(1)
+	}
+	fmt.Println(AddColor("OK", constants.Green))
+	fmt.Println(strconv.Itoa(len(results)), "backup(s) deleted.")
+}
+
+// instanceNameOf resolves and caches the name of an instance, falling back to the guid for deleted instances.
+func instanceNameOf(cliConnection plugin.CliConnection, instanceGuid string, cache map[string]string) string {
+	if name, flag := cache[instanceGuid]; flag {
+		return name
+	}
+	var name string = strings.Trim(guidTranslator.FindInstanceName(cliConnection, instanceGuid, nil), "\"")
+	if name == "" {
+		name = instanceGuid + " (deleted)"
+	}
+	cache[instanceGuid] = name
+	return name
+}
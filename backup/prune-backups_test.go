@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBackup(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Backup Suite")
+}
+
+func deletedGuids(decisions []PruneDecision) []string {
+	var guids []string
+	for _, decision := range decisions {
+		if decision.Delete {
+			guids = append(guids, decision.Backup.BackupGuid)
+		}
+	}
+	return guids
+}
+
+var _ = Describe("prune backups", func() {
+	now, _ := time.Parse(time.RFC3339, "2018-11-30T00:00:00Z")
+
+	newRecords := func() []BackupRecord {
+		return []BackupRecord{
+			{BackupGuid: "b1", InstanceGuid: "i1", Trigger: "scheduled", State: "succeeded", StartedAt: "2018-11-01T00:00:00Z", FinishedAt: "2018-11-01T00:10:00Z"},
+			{BackupGuid: "b2", InstanceGuid: "i1", Trigger: "on-demand", State: "succeeded", StartedAt: "2018-11-10T00:00:00Z", FinishedAt: "2018-11-10T00:10:00Z"},
+			{BackupGuid: "b3", InstanceGuid: "i1", Trigger: "on-demand", State: "failed", StartedAt: "2018-11-20T00:00:00Z", FinishedAt: "2018-11-20T00:10:00Z"},
+			{BackupGuid: "b4", InstanceGuid: "i1", Trigger: "on-demand", State: "processing", StartedAt: "2018-11-29T00:00:00Z"},
+			{BackupGuid: "b5", InstanceGuid: "i2", Trigger: "on-demand", State: "succeeded", StartedAt: "2018-10-01T00:00:00Z", FinishedAt: "2018-10-01T00:10:00Z"},
+		}
+	}
+
+	Context("Keeping the last backups", func() {
+		It("Older and failed backups should be deleted", func() {
+			decisions := PlanPrune(newRecords(), 2, 0, false, now)
+			Expect(deletedGuids(decisions)).To(Equal([]string{"b3"}))
+			decisions = PlanPrune(newRecords(), 1, 0, false, now)
+			Expect(deletedGuids(decisions)).To(Equal([]string{"b3", "b1"}))
+		})
+		It("A run of failed backups should not push the successful ones out", func() {
+			records := []BackupRecord{
+				{BackupGuid: "c1", InstanceGuid: "i3", State: "succeeded", StartedAt: "2018-11-01T00:00:00Z"},
+				{BackupGuid: "c2", InstanceGuid: "i3", State: "succeeded", StartedAt: "2018-11-02T00:00:00Z"},
+				{BackupGuid: "c3", InstanceGuid: "i3", State: "failed", StartedAt: "2018-11-03T00:00:00Z"},
+				{BackupGuid: "c4", InstanceGuid: "i3", State: "failed", StartedAt: "2018-11-04T00:00:00Z"},
+				{BackupGuid: "c5", InstanceGuid: "i3", State: "failed", StartedAt: "2018-11-05T00:00:00Z"},
+			}
+			decisions := PlanPrune(records, 2, 0, false, now)
+			Expect(deletedGuids(decisions)).To(Equal([]string{"c5", "c4", "c3"}))
+		})
+		It("Only on-demand backups should count with --only-on-demand", func() {
+			records := []BackupRecord{
+				{BackupGuid: "d1", InstanceGuid: "i4", Trigger: "on-demand", State: "succeeded", StartedAt: "2018-11-01T00:00:00Z"},
+				{BackupGuid: "d2", InstanceGuid: "i4", Trigger: "scheduled", State: "succeeded", StartedAt: "2018-11-02T00:00:00Z"},
+				{BackupGuid: "d3", InstanceGuid: "i4", Trigger: "on-demand", State: "succeeded", StartedAt: "2018-11-03T00:00:00Z"},
+				{BackupGuid: "d4", InstanceGuid: "i4", Trigger: "scheduled", State: "succeeded", StartedAt: "2018-11-04T00:00:00Z"},
+				{BackupGuid: "d5", InstanceGuid: "i4", Trigger: "scheduled", State: "succeeded", StartedAt: "2018-11-05T00:00:00Z"},
+			}
+			decisions := PlanPrune(records, 2, 0, true, now)
+			Expect(deletedGuids(decisions)).To(BeEmpty())
+			decisions = PlanPrune(records, 1, 0, true, now)
+			Expect(deletedGuids(decisions)).To(Equal([]string{"d1"}))
+		})
+	})
+
+	Context("Deleting backups older than a threshold", func() {
+		It("The newest successful backup of each instance should be kept", func() {
+			decisions := PlanPrune(newRecords(), 0, 15*24*time.Hour, false, now)
+			Expect(deletedGuids(decisions)).To(Equal([]string{"b1"}))
+		})
+		It("Only on-demand backups should be deleted", func() {
+			decisions := PlanPrune(newRecords(), 0, 5*24*time.Hour, true, now)
+			Expect(deletedGuids(decisions)).To(Equal([]string{"b3"}))
+		})
+	})
+})
@@ -0,0 +1,78 @@
+package backup
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// DescribeActivity lists the operations which keep an instance busy: the cloud controller last_operation while it is in
+// progress, backups in progress and the last restore while it is in progress. The instance is idle if the list is empty.
+func DescribeActivity(lastOperation map[string]interface{}, backups []BackupRecord, restoreStatus map[string]interface{}) []string {
+	var activities []string
+	if guidTranslator.StringField(lastOperation, "state") == constants.OperationStateInProgress {
+		activities = append(activities, "service instance "+guidTranslator.StringField(lastOperation, "type")+" in progress")
+	}
+	for _, record := range backups {
+		if record.IsInProgress() {
+			activities = append(activities, "backup "+record.BackupGuid+" "+record.State+" since "+record.StartedAt)
+		}
+	}
+	if state, _ := restoreStatus["state"].(string); state == constants.BackupStateProcessing || state == constants.BackupStateAborting {
+		startedAt, _ := restoreStatus["started_at"].(string)
+		activities = append(activities, "restore "+state+" since "+startedAt)
+	}
+	return activities
+}
+
+// FindInstanceActivity returns the operations which keep the instance busy, see DescribeActivity.
+func FindInstanceActivity(cliConnection plugin.CliConnection, client *http.Client, instanceGuid string, userSpaceGuid string) ([]string, error) {
+	instance, err := guidTranslator.CurlObject(cliConnection, "/v2/service_instances/"+instanceGuid)
+	if err != nil {
+		return nil, err
+	}
+	lastOperation, _ := guidTranslator.Entity(instance)["last_operation"].(map[string]interface{})
+
+	backups, err := GetBackupRecords(client, userSpaceGuid, instanceGuid)
+	if err != nil {
+		return nil, err
+	}
+	restoreStatus, err := helper.GetRestoreStatus(client, instanceGuid, userSpaceGuid)
+	if err != nil {
+		return nil, err
+	}
+	return DescribeActivity(lastOperation, backups, restoreStatus), nil
+}
+
+// WaitForIdle polls the instance until no operation keeps it busy, reporting what it waits for whenever that changes.
+// It fails if the instance is not idle within the timeout.
+func WaitForIdle(cliConnection plugin.CliConnection, client *http.Client, instanceGuid string, userSpaceGuid string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	var reported string
+	for {
+		activities, err := FindInstanceActivity(cliConnection, client, instanceGuid, userSpaceGuid)
+		if err != nil {
+			return err
+		}
+		if len(activities) == 0 {
+			if reported != "" {
+				fmt.Println("  the instance is idle")
+			}
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("the instance is not idle after %s: %s", timeout, strings.Join(activities, ", "))
+		}
+		if current := strings.Join(activities, ", "); current != reported {
+			fmt.Println("  waiting for:", current, "...")
+			reported = current
+		}
+		time.Sleep(time.Duration(constants.PollInterval) * time.Second)
+	}
+}
@@ -0,0 +1,29 @@
+package backup
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("wait for idle", func() {
+	It("An instance without operations in progress should be idle", func() {
+		lastOperation := map[string]interface{}{"type": "update", "state": "succeeded"}
+		backups := []BackupRecord{{BackupGuid: "b1", State: "succeeded", StartedAt: "2018-11-29T00:00:00Z"}}
+		restoreStatus := map[string]interface{}{"state": "failed"}
+		Expect(DescribeActivity(lastOperation, backups, restoreStatus)).To(BeEmpty())
+		Expect(DescribeActivity(nil, nil, nil)).To(BeEmpty())
+	})
+	It("Updates, backups and restores in progress should be reported", func() {
+		lastOperation := map[string]interface{}{"type": "update", "state": "in progress"}
+		backups := []BackupRecord{
+			{BackupGuid: "b1", State: "succeeded", StartedAt: "2018-11-29T00:00:00Z"},
+			{BackupGuid: "b2", State: "processing", StartedAt: "2018-11-30T00:00:00Z"},
+		}
+		restoreStatus := map[string]interface{}{"state": "processing", "started_at": "2018-11-30T00:01:00Z"}
+		Expect(DescribeActivity(lastOperation, backups, restoreStatus)).To(Equal([]string{
+			"service instance update in progress",
+			"backup b2 processing since 2018-11-30T00:00:00Z",
+			"restore processing since 2018-11-30T00:01:00Z",
+		}))
+	})
+})
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+	"github.com/fatih/color"
+)
+
+type ConfigCommand struct {
+	cliConnection plugin.CliConnection
+}
+
+func NewConfigCommand(cliConnection plugin.CliConnection) *ConfigCommand {
+	command := new(ConfigCommand)
+	command.cliConnection = cliConnection
+	return command
+}
+
+func AddColor(text string, textColor color.Attribute) string {
+	printer := color.New(textColor).Add(color.Bold).SprintFunc()
+	return printer(text)
+}
+
+func failConfig(err error) {
+	fmt.Println(AddColor("FAILED", constants.Red))
+	fmt.Println(err)
+	os.Exit(1)
+}
+
+// readConfFile reads conf.json and returns it with the name of the profile the command acts on. A profile given with
+// --profile which does not exist yet is created with the default settings if create is set.
+func readConfFile(create bool) (helper.ConfFile, string) {
+	conf, err := helper.ReadConfFile()
+	if err != nil {
+		errors.InvalidConfiguration(helper.GetConfFilePath(), err)
+	}
+	if profile := helper.GetProfile(); profile != "" && create {
+		if _, _, err := conf.Select(profile, ""); err != nil {
+			if conf.Profiles == nil {
+				conf.Profiles = make(map[string]helper.Configuration)
+			}
+			conf.Profiles[profile] = helper.DefaultConfiguration()
+		}
+	}
+	profile, _, err := conf.Select(helper.GetProfile(), helper.CurrentTarget())
+	if err != nil {
+		failConfig(err)
+	}
+	return conf, profile
+}
+
+// Get prints the value of the setting stored in conf.json, without environment overrides.
+func (c *ConfigCommand) Get(key string) {
+	conf, profile := readConfFile(false)
+	value, err := conf.Profiles[profile].Get(key)
+	if err != nil {
+		failConfig(err)
+	}
+	fmt.Println(value)
+}
+
+// Set validates the value and stores it in the profile in use. conf.json is replaced atomically.
+func (c *ConfigCommand) Set(key string, value string) {
+	conf, profile := readConfFile(true)
+	fmt.Println("Setting", AddColor(key, constants.Cyan), "to", AddColor(value, constants.Cyan), "in the profile", AddColor(profile, constants.Cyan), "...")
+
+	configuration := conf.Profiles[profile]
+	if err := configuration.Set(key, value); err != nil {
+		failConfig(err)
+	}
+	c.write(conf, profile, configuration, key)
+}
+
+// Unset resets the setting of the profile in use to its default value.
+func (c *ConfigCommand) Unset(key string) {
+	conf, profile := readConfFile(false)
+	fmt.Println("Resetting", AddColor(key, constants.Cyan), "in the profile", AddColor(profile, constants.Cyan), "...")
+
+	configuration := conf.Profiles[profile]
+	if err := configuration.Unset(key); err != nil {
+		failConfig(err)
+	}
+	c.write(conf, profile, configuration, key)
+}
+
+func (c *ConfigCommand) write(conf helper.ConfFile, profile string, configuration helper.Configuration, key string) {
+	conf.Profiles[profile] = configuration
+	if err := helper.WriteConfFile(conf); err != nil {
+		failConfig(err)
+	}
+	fmt.Println(AddColor("OK", constants.Green))
+	if variable := helper.ConfEnvironmentVariable(key); variable != "" {
+		if _, flag := os.LookupEnv(variable); flag {
+			fmt.Println("Note: the setting is overridden by the environment variable", variable+".")
+		}
+	}
+}
+
+// List shows the effective settings of the profile in use and where each of them comes from.
+func (c *ConfigCommand) List() {
+	conf, profile := readConfFile(false)
+	fmt.Println("Getting the settings of the profile", AddColor(profile, constants.Cyan), "in", AddColor(helper.GetConfFilePath(), constants.Cyan), "...")
+
+	configuration, overridden, err := helper.ApplyEnvironment(conf.Profiles[profile])
+	if err != nil {
+		failConfig(err)
+	}
+
+	fmt.Println(AddColor("OK", constants.Green))
+	table := backup.NewTable()
+	table.SetHeader([]string{AddColor("setting", constants.White), AddColor("value", constants.White), AddColor("source", constants.White)})
+	for _, key := range helper.ConfKeys {
+		value, _ := configuration.Get(key)
+		var source string = "profile " + profile
+		if variable, flag := overridden[key]; flag {
+			source = "environment " + variable
+		}
+		table.Append([]string{key, value, source})
+	}
+	table.Render()
+	fmt.Println("Profiles:", strings.Join(conf.ProfileNames(), ", "))
+}
+
+// Validate checks conf.json and the SF_* environment variables and fails if any of them is invalid.
+func (c *ConfigCommand) Validate() {
+	fmt.Println("Validating", AddColor(helper.GetConfFilePath(), constants.Cyan), "...")
+
+	content, err := ioutil.ReadFile(helper.GetConfFilePath())
+	if err != nil {
+		failConfig(err)
+	}
+	problems := helper.ValidateConfFile(content)
+	if _, _, err := helper.ApplyEnvironment(helper.Configuration{}); err != nil {
+		problems["environment"] = append(problems["environment"], err)
+	}
+	if len(problems) == 0 {
+		fmt.Println(AddColor("OK", constants.Green))
+		return
+	}
+
+	var names []string
+	for name := range problems {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Println(AddColor("FAILED", constants.Red))
+	for _, name := range names {
+		for _, problem := range problems[name] {
+			if name == "" {
+				fmt.Println(problem)
+			} else {
+				fmt.Println(name+":", problem)
+			}
+		}
+	}
+	os.Exit(1)
+}
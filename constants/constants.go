@@ -8,11 +8,29 @@ const (
 	Red                        color.Attribute = color.FgRed
 	Green                      color.Attribute = color.FgGreen
 	Cyan                       color.Attribute = color.FgCyan
+	Yellow                     color.Attribute = color.FgYellow
 	White                      color.Attribute = color.FgWhite
 	MaxIdleConnections         int             = 25
 	RequestTimeout             int             = 180
 	OKHttpStatusResponse       string          = "200 OK"
 	AcceptedHttpStatusResponse string          = "202 Accepted"
+	MaxConcurrentRequests      int             = 5
+	PollInterval               int             = 15
+	OperationTimeout           int             = 7200
+	FollowInterval             int             = 5
+	MaxRetries                 int             = 3
+	RetryDelay                 int             = 1
+	MaxRetryDelay              int             = 30
+	BackupStateSucceeded       string          = "succeeded"
+	BackupStateProcessing      string          = "processing"
+	BackupStateAborting        string          = "aborting"
+	BackupTriggerOnDemand      string          = "on-demand"
+	OperationStateInProgress   string          = "in progress"
+	OperationStateSucceeded    string          = "succeeded"
+	OperationStateFailed       string          = "failed"
+	CheckExitOK                int             = 0
+	CheckExitViolations        int             = 2
+	CheckExitUnknown           int             = 3
 )
 
 var ValidServices = []string{"blueprint", "postgresql", "mongodb", "redis"}
@@ -0,0 +1,302 @@
+package doctor
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+	"github.com/fatih/color"
+)
+
+const (
+	StatusPass string = "pass"
+	StatusWarn string = "warn"
+	StatusFail string = "fail"
+	StatusSkip string = "skipped"
+)
+
+// CheckResult is the outcome of one check of the doctor.
+type CheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type DoctorCommand struct {
+	cliConnection plugin.CliConnection
+}
+
+func NewDoctorCommand(cliConnection plugin.CliConnection) *DoctorCommand {
+	command := new(DoctorCommand)
+	command.cliConnection = cliConnection
+	return command
+}
+
+func AddColor(text string, textColor color.Attribute) string {
+	printer := color.New(textColor).Add(color.Bold).SprintFunc()
+	return printer(text)
+}
+
+func result(name string, status string, message string) CheckResult {
+	return CheckResult{Name: name, Status: status, Message: message}
+}
+
+// CheckCfConfig checks that config.json could be read and holds a target, an org, a space and a token.
+func CheckCfConfig(content []byte, readErr error) (CheckResult, helper.Config) {
+	var config helper.Config
+	if readErr != nil {
+		return result("config.json", StatusFail, readErr.Error()), config
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return result("config.json", StatusFail, "invalid JSON: "+err.Error()), config
+	}
+
+	var missing []string
+	if config.Target == "" {
+		missing = append(missing, "API endpoint (cf api)")
+	}
+	if config.AccessToken == "" {
+		missing = append(missing, "access token (cf login)")
+	}
+	if config.OrganizationFields.GUID == "" {
+		missing = append(missing, "org (cf target -o)")
+	}
+	if config.SpaceFields.GUID == "" {
+		missing = append(missing, "space (cf target -s)")
+	}
+	if len(missing) > 0 {
+		return result("config.json", StatusFail, "missing "+strings.Join(missing, ", ")), config
+	}
+	return result("config.json", StatusPass, "target "+config.Target+", org "+config.OrganizationFields.Name+", space "+config.SpaceFields.Name), config
+}
+
+// CheckToken checks that the access token is not expired and has the scopes required by the plugin.
+func CheckToken(accessToken string, now time.Time) CheckResult {
+	info := helper.NewTokenInfo(accessToken)
+	if info.UserGUID == "" && info.Expiry == 0 {
+		return result("token", StatusFail, "the access token cannot be decoded, please log in again")
+	}
+
+	if missing := info.MissingScopes(helper.RequiredScopes...); len(missing) > 0 {
+		return result("token", StatusFail, "the token of "+info.Username+" lacks the scopes "+strings.Join(missing, ", "))
+	}
+
+	expiry := time.Unix(info.Expiry, 0)
+	if !expiry.After(now) {
+		return result("token", StatusFail, "the token of "+info.Username+" expired at "+expiry.UTC().Format(time.RFC3339)+", run 'cf oauth-token' to refresh it")
+	}
+	if expiry.Sub(now) < 5*time.Minute {
+		return result("token", StatusWarn, "the token of "+info.Username+" expires at "+expiry.UTC().Format(time.RFC3339)+", run 'cf oauth-token' to refresh it")
+	}
+	return result("token", StatusPass, "user "+info.Username+", valid until "+expiry.UTC().Format(time.RFC3339))
+}
+
+// CheckConfFile checks that conf.json is valid, that the profile in use exists and that the SF_* environment variables
+// have valid values.
+func CheckConfFile(content []byte, readErr error) CheckResult {
+	if readErr != nil {
+		return result("conf.json", StatusFail, readErr.Error())
+	}
+	var messages []string
+	for name, problems := range helper.ValidateConfFile(content) {
+		for _, problem := range problems {
+			if name != "" {
+				messages = append(messages, "profile "+name+": "+problem.Error())
+			} else {
+				messages = append(messages, problem.Error())
+			}
+		}
+	}
+	if _, _, err := helper.ApplyEnvironment(helper.Configuration{}); err != nil {
+		messages = append(messages, err.Error())
+	}
+	if len(messages) > 0 {
+		return result("conf.json", StatusFail, strings.Join(messages, "; "))
+	}
+
+	conf, _, err := helper.ParseConfFile(content)
+	if err != nil {
+		return result("conf.json", StatusFail, err.Error())
+	}
+	profile, _, err := conf.Select(helper.GetProfile(), helper.CurrentTarget())
+	if err != nil {
+		return result("conf.json", StatusFail, err.Error())
+	}
+	return result("conf.json", StatusPass, "valid, using the profile "+profile)
+}
+
+// checkBrokerHost checks that the hostname of the broker resolves and that its certificate verifies.
+func checkBrokerHost(brokerApiUrl string) []CheckResult {
+	parsed, err := url.Parse(brokerApiUrl)
+	if err != nil || parsed.Hostname() == "" {
+		return []CheckResult{result("broker DNS", StatusFail, "invalid broker URL "+brokerApiUrl), result("broker TLS", StatusSkip, "")}
+	}
+	addresses, err := net.LookupHost(parsed.Hostname())
+	if err != nil {
+		return []CheckResult{result("broker DNS", StatusFail, err.Error()), result("broker TLS", StatusSkip, "")}
+	}
+	dnsResult := result("broker DNS", StatusPass, parsed.Hostname()+" resolves to "+strings.Join(addresses, ", "))
+
+	if parsed.Scheme != "https" {
+		return []CheckResult{dnsResult, result("broker TLS", StatusWarn, "the broker is called without TLS")}
+	}
+	var port string = parsed.Port()
+	if port == "" {
+		port = "443"
+	}
+	dialer := &net.Dialer{Timeout: time.Duration(constants.RequestTimeout) * time.Second}
+	connection, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(parsed.Hostname(), port), helper.NewTLSConfig(false))
+	if err != nil {
+		return []CheckResult{dnsResult, result("broker TLS", StatusFail, err.Error())}
+	}
+	defer connection.Close()
+	if helper.SkipSslVerification() {
+		return []CheckResult{dnsResult, result("broker TLS", StatusWarn, "the certificate verifies, but verification is disabled in the configuration")}
+	}
+	return []CheckResult{dnsResult, result("broker TLS", StatusPass, "the certificate verifies")}
+}
+
+// checkBroker checks that the broker answers the listing of the backups of the space.
+func checkBroker(brokerApiUrl string, userSpaceGuid string) CheckResult {
+	resp, body, err := helper.CallBroker(backup.GetHttpClient(), "GET", brokerApiUrl+"/backups?space_guid="+userSpaceGuid, nil)
+	if err != nil {
+		return result("broker", StatusFail, err.Error())
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return result("broker", StatusPass, brokerApiUrl+" answers")
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return result("broker", StatusFail, brokerApiUrl+" denies access: "+helper.BrokerError(resp, body).Error())
+	}
+	return result("broker", StatusFail, brokerApiUrl+" answers with "+helper.BrokerError(resp, body).Error())
+}
+
+// checkCloudController checks that the given cloud controller API answers.
+func checkCloudController(cliConnection plugin.CliConnection, name string, path string) CheckResult {
+	if _, err := guidTranslator.CurlObject(cliConnection, path); err != nil {
+		return result(name, StatusFail, err.Error())
+	}
+	return result(name, StatusPass, path+" answers")
+}
+
+// checkInstances checks that the space has at least one instance of a Service Fabrik service.
+func checkInstances(cliConnection plugin.CliConnection, userSpaceGuid string) CheckResult {
+	instances, err := guidTranslator.FindSpaceInstances(cliConnection, userSpaceGuid)
+	if err != nil {
+		return result("instances", StatusFail, err.Error())
+	}
+	var count int
+	for _, instance := range instances {
+		if guidTranslator.IsServiceNameValid(instance.ServiceLabel) {
+			count++
+		}
+	}
+	if count == 0 {
+		return result("instances", StatusWarn, "no instance of the services "+strings.Join(constants.ValidServices, ", ")+" in the space")
+	}
+	return result("instances", StatusPass, strconv.Itoa(count)+" Service Fabrik instance(s) in the space")
+}
+
+// RunChecks runs all checks in order. Checks which depend on a failed check are skipped.
+func RunChecks(cliConnection plugin.CliConnection) []CheckResult {
+	var results []CheckResult
+
+	configContent, err := ioutil.ReadFile(helper.GetCfConfigDir() + string(os.PathSeparator) + "config.json")
+	configResult, config := CheckCfConfig(configContent, err)
+	results = append(results, configResult)
+	var loggedIn bool = configResult.Status != StatusFail
+	if config.AccessToken != "" {
+		results = append(results, CheckToken(config.AccessToken, time.Now()))
+		loggedIn = loggedIn && results[len(results)-1].Status != StatusFail
+	} else {
+		results = append(results, result("token", StatusSkip, "no access token"))
+	}
+
+	confContent, err := ioutil.ReadFile(helper.GetConfFilePath())
+	confResult := CheckConfFile(confContent, err)
+	results = append(results, confResult)
+
+	if !loggedIn || confResult.Status == StatusFail {
+		for _, name := range []string{"broker DNS", "broker TLS", "broker", "CC v2", "CC v3", "instances"} {
+			results = append(results, result(name, StatusSkip, "depends on a failed check"))
+		}
+		return results
+	}
+
+	var userSpaceGuid string = helper.GetSpaceGUID(configContent) //Honours --org, --space and --space-guid.
+	brokerApiUrl, source := helper.ResolveBrokerApiUrl()
+	hostResults := checkBrokerHost(brokerApiUrl)
+	for index := range hostResults {
+		if hostResults[index].Name == "broker DNS" && hostResults[index].Status == StatusPass {
+			hostResults[index].Message = hostResults[index].Message + " (broker URL from " + source + ")"
+		}
+	}
+	results = append(results, hostResults...)
+	if hostResults[0].Status == StatusFail {
+		results = append(results, result("broker", StatusSkip, "depends on a failed check"))
+	} else {
+		results = append(results, checkBroker(brokerApiUrl, userSpaceGuid))
+	}
+
+	results = append(results, checkCloudController(cliConnection, "CC v2", "/v2/info"))
+	results = append(results, checkCloudController(cliConnection, "CC v3", "/v3"))
+	results = append(results, checkInstances(cliConnection, userSpaceGuid))
+	return results
+}
+
+// RunDoctor prints the report of all checks and fails if any check failed.
+func (c *DoctorCommand) RunDoctor(cliConnection plugin.CliConnection, jsonOutput bool) {
+	if !jsonOutput {
+		fmt.Println("Checking the plugin setup ...")
+	}
+	results := RunChecks(cliConnection)
+
+	var failed bool
+	for _, check := range results {
+		if check.Status == StatusFail {
+			failed = true
+		}
+	}
+
+	if jsonOutput {
+		output, _ := json.MarshalIndent(map[string]interface{}{"checked_at": time.Now().UTC().Format(time.RFC3339), "failed": failed, "checks": results}, "", "  ")
+		fmt.Println(string(output))
+	} else {
+		table := backup.NewTable()
+		table.SetHeader([]string{AddColor("check", constants.White), AddColor("status", constants.White), AddColor("detail", constants.White)})
+		for _, check := range results {
+			var status string = check.Status
+			switch check.Status {
+			case StatusPass:
+				status = AddColor(status, constants.Green)
+			case StatusWarn:
+				status = AddColor(status, constants.Yellow)
+			case StatusFail:
+				status = AddColor(status, constants.Red)
+			}
+			table.Append([]string{check.Name, status, check.Message})
+		}
+		table.Render()
+		if failed {
+			fmt.Println(AddColor("FAILED", constants.Red))
+		} else {
+			fmt.Println(AddColor("OK", constants.Green))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,58 @@
+package doctor
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestDoctor(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Doctor Suite")
+}
+
+func accessToken(payload string) string {
+	return "bearer eyJhbGciOiJSUzI1NiJ9." + base64.RawStdEncoding.EncodeToString([]byte(payload)) + ".c2lnbmF0dXJl"
+}
+
+var _ = Describe("doctor", func() {
+	now := time.Unix(1500547000, 0)
+
+	Context("Checking config.json", func() {
+		It("A missing space should fail the check", func() {
+			check, config := CheckCfConfig([]byte(`{"Target":"https://api.example.com","AccessToken":"bearer x","OrganizationFields":{"GUID":"o1","Name":"org"}}`), nil)
+			Expect(check.Status).To(Equal(StatusFail))
+			Expect(check.Message).To(ContainSubstring("space (cf target -s)"))
+			Expect(config.Target).To(Equal("https://api.example.com"))
+		})
+		It("A file which cannot be read should fail the check", func() {
+			check, _ := CheckCfConfig(nil, errors.New("no such file"))
+			Expect(check.Status).To(Equal(StatusFail))
+		})
+	})
+
+	Context("Checking the token", func() {
+		It("A valid token with the required scopes should pass", func() {
+			check := CheckToken(accessToken(`{"user_id":"u1","user_name":"admin","scope":["cloud_controller.read","cloud_controller.write"],"exp":1500547973}`), now)
+			Expect(check.Status).To(Equal(StatusPass))
+		})
+		It("An expired token should fail", func() {
+			check := CheckToken(accessToken(`{"user_id":"u1","user_name":"admin","scope":["cloud_controller.admin"],"exp":1500546000}`), now)
+			Expect(check.Status).To(Equal(StatusFail))
+			Expect(check.Message).To(ContainSubstring("cf oauth-token"))
+		})
+		It("A token about to expire should warn", func() {
+			check := CheckToken(accessToken(`{"user_id":"u1","user_name":"admin","scope":["cloud_controller.admin"],"exp":1500547100}`), now)
+			Expect(check.Status).To(Equal(StatusWarn))
+		})
+		It("A token without the write scope should fail", func() {
+			check := CheckToken(accessToken(`{"user_id":"u1","user_name":"auditor","scope":["cloud_controller.read"],"exp":1500547973}`), now)
+			Expect(check.Status).To(Equal(StatusFail))
+			Expect(check.Message).To(ContainSubstring("cloud_controller.write"))
+		})
+	})
+})
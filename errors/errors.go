@@ -50,6 +50,13 @@ func IncorrectSpace(orgName string, spaceName string) {
 	os.Exit(2)
 }
 
+func InstanceInOtherSpace(instanceName string, orgName string, spaceName string) {
+	color.Red("FAILED")
+	fmt.Println("Service Instance \"" + instanceName + "\" belongs to the org: " + orgName + " and the space: " + spaceName + ".")
+	fmt.Println("Please target that space, e.g. with --org " + orgName + " --space " + spaceName + ".")
+	os.Exit(2)
+}
+
 func IncorrectInstanceName(instanceName string) {
 	color.Red("FAILED")
 	fmt.Println("Service Instance \"" + instanceName + "\" doesn't exist.")
@@ -81,6 +88,13 @@ func FileReadingError(filename string) {
 	os.Exit(5)
 }
 
+func InvalidConfiguration(filename string, err error) {
+	color.Red("FAILED")
+	fmt.Println("Invalid plugin configuration in", filename+":", err)
+	fmt.Println("Enter 'cf sf-config validate' to check the configuration.")
+	os.Exit(5)
+}
+
 func NoAccessTokenError(val string) {
 	color.Red("FAILED")
 	fmt.Println("No " + val + " was found.")
@@ -88,6 +102,20 @@ func NoAccessTokenError(val string) {
 	os.Exit(6)
 }
 
+func MissingScopes(username string, scopes string) {
+	color.Red("FAILED")
+	fmt.Println("The token of " + username + " lacks the scopes " + scopes + " required for this command.")
+	fmt.Println("Please log in again or ask your administrator for the scopes.")
+	os.Exit(8)
+}
+
+func MissingRole(role string, orgName string, spaceName string) {
+	color.Red("FAILED")
+	fmt.Println("You need the " + role + " role in the org: " + orgName + " and the space: " + spaceName + " for this command.")
+	fmt.Println("Enter 'cf sf-whoami' to check your roles.")
+	os.Exit(8)
+}
+
 func HomeDirNotFound(err error) {
 	color.Red("FAILED")
 	log.Fatal(err)
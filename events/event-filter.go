@@ -0,0 +1,145 @@
+package events
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+)
+
+// eventTypeGroups maps the groups accepted by --types to the cloud controller event types they stand for.
+var eventTypeGroups = map[string][]string{
+	"instances":      {"audit.service_instance.create", "audit.service_instance.update", "audit.service_instance.delete"},
+	"bindings":       {"audit.service_binding.create", "audit.service_binding.delete"},
+	"keys":           {"audit.service_key.create", "audit.service_key.delete"},
+	"route-bindings": {"audit.service_instance.bind_route", "audit.service_instance.unbind_route"},
+}
+
+// EventFilter selects the audit events listed by instance-events. Zero values select everything.
+type EventFilter struct {
+	Groups       []string
+	Action       string
+	InstanceGuid string
+	User         string
+	Since        time.Time
+	Until        time.Time
+	Limit        int
+}
+
+// eventAction returns create, update or delete for the given event type. Binding a route counts as create, unbinding as delete.
+func eventAction(eventType string) string {
+	switch {
+	case strings.HasSuffix(eventType, ".unbind_route"):
+		return "delete"
+	case strings.HasSuffix(eventType, ".bind_route"):
+		return "create"
+	}
+	return eventType[strings.LastIndex(eventType, ".")+1:]
+}
+
+// ParseEventGroups parses the comma separated value of --types. "instances" is always included.
+func ParseEventGroups(value string) ([]string, error) {
+	groups := []string{"instances"}
+	for _, group := range strings.Split(value, ",") {
+		group = strings.TrimSpace(group)
+		if group == "" || group == "instances" {
+			continue
+		}
+		if _, flag := eventTypeGroups[group]; !flag {
+			return nil, errors.New("unknown event type " + group + ", expected instances, bindings, keys or route-bindings")
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// EventTypes returns the cloud controller event types selected by the filter, sorted.
+func (filter EventFilter) EventTypes() []string {
+	groups := filter.Groups
+	if len(groups) == 0 {
+		groups = []string{"instances"}
+	}
+	var types []string
+	for _, group := range groups {
+		for _, eventType := range eventTypeGroups[group] {
+			if filter.Action == "" || eventAction(eventType) == filter.Action {
+				types = append(types, eventType)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
+// instanceIsActee reports whether the service instance is the actee of all selected event types, so that
+// the instance can be filtered by the cloud controller instead of the plugin.
+func (filter EventFilter) instanceIsActee() bool {
+	for _, eventType := range filter.EventTypes() {
+		if !strings.HasPrefix(eventType, "audit.service_instance.") {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the /v2/events path for the filter in the given space. The type, space, actee and time filters are
+// applied by the cloud controller. With a limit, the newest events are requested first.
+func (filter EventFilter) Query(userSpaceGuid string) string {
+	var cmd string = "/v2/events?q=type+IN+" + strings.Join(filter.EventTypes(), ",") + "%3Bspace_guid:" + userSpaceGuid
+	if filter.InstanceGuid != "" && filter.instanceIsActee() {
+		cmd = cmd + "%3Bactee:" + filter.InstanceGuid
+	}
+	if !filter.Since.IsZero() {
+		cmd = cmd + "%3Btimestamp" + url.QueryEscape(">="+filter.Since.UTC().Format(time.RFC3339))
+	}
+	if !filter.Until.IsZero() {
+		cmd = cmd + "%3Btimestamp" + url.QueryEscape("<"+filter.Until.UTC().Format(time.RFC3339))
+	}
+	if filter.Limit > 0 {
+		var pageSize int = filter.Limit
+		if pageSize > 100 {
+			pageSize = 100
+		}
+		cmd = cmd + "&order-direction=desc&results-per-page=" + strconv.Itoa(pageSize)
+	}
+	return cmd
+}
+
+// InstanceLookup returns the guid of the service instance of the binding or key with the given guid, or "" if it is unknown.
+type InstanceLookup func(acteeGuid string) string
+
+// isBindingOrKeyEvent reports whether the actee of the event is a service binding or key.
+func isBindingOrKeyEvent(eventType string) bool {
+	return strings.HasPrefix(eventType, "audit.service_binding.") || strings.HasPrefix(eventType, "audit.service_key.")
+}
+
+// EventInstanceGuid returns the guid of the service instance the event belongs to. For binding and key events
+// this is taken from the request, as their actee is the binding or key. The request of a delete event does not
+// carry the instance, so it is found by lookup, if given. If the instance is unknown, the actee guid is returned.
+func EventInstanceGuid(event guidTranslator.InstanceEvent, lookup InstanceLookup) string {
+	if instanceGuid := guidTranslator.StringField(event.Request, "service_instance_guid"); instanceGuid != "" {
+		return instanceGuid
+	}
+	if lookup != nil && isBindingOrKeyEvent(event.Type) {
+		if instanceGuid := lookup(event.InstanceGuid); instanceGuid != "" {
+			return instanceGuid
+		}
+	}
+	return event.InstanceGuid
+}
+
+// Matches applies the parts of the filter which the cloud controller cannot apply. lookup resolves the instance of
+// binding and key delete events, see EventInstanceGuid.
+func (filter EventFilter) Matches(event guidTranslator.InstanceEvent, lookup InstanceLookup) bool {
+	if filter.User != "" && !strings.EqualFold(event.Actor, filter.User) {
+		return false
+	}
+	if filter.InstanceGuid != "" && EventInstanceGuid(event, lookup) != filter.InstanceGuid {
+		return false
+	}
+	return true
+}
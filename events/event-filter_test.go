@@ -0,0 +1,43 @@
+package events
+
+import (
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("event filter", func() {
+	It("The action should restrict the event types of all groups", func() {
+		groups, err := ParseEventGroups("keys,route-bindings")
+		Expect(err).NotTo(HaveOccurred())
+		filter := EventFilter{Groups: groups, Action: "delete"}
+		Expect(filter.EventTypes()).To(Equal([]string{"audit.service_instance.delete", "audit.service_instance.unbind_route", "audit.service_key.delete"}))
+	})
+	It("Unknown event types should be rejected", func() {
+		_, err := ParseEventGroups("bindings,apps")
+		Expect(err).To(HaveOccurred())
+	})
+	It("Time, instance and limit filters should be passed to the cloud controller", func() {
+		since, _ := time.Parse(time.RFC3339, "2018-11-01T00:00:00Z")
+		filter := EventFilter{Action: "update", InstanceGuid: "i1", Since: since, Limit: 500}
+		Expect(filter.Query("s1")).To(Equal("/v2/events?q=type+IN+audit.service_instance.update%3Bspace_guid:s1%3Bactee:i1%3Btimestamp%3E%3D2018-11-01T00%3A00%3A00Z&order-direction=desc&results-per-page=100"))
+	})
+	It("Binding events should match the instance given in their request", func() {
+		filter := EventFilter{Groups: []string{"instances", "bindings"}, InstanceGuid: "i1", User: "Admin"}
+		Expect(filter.Query("s1")).NotTo(ContainSubstring("actee"))
+		Expect(filter.Matches(guidTranslator.InstanceEvent{InstanceGuid: "b1", Actor: "admin", Request: map[string]interface{}{"service_instance_guid": "i1"}}, nil)).To(BeTrue())
+		Expect(filter.Matches(guidTranslator.InstanceEvent{InstanceGuid: "i1", Actor: "bob"}, nil)).To(BeFalse())
+	})
+	It("Binding and key delete events should match the instance of their create event", func() {
+		filter := EventFilter{Groups: []string{"instances", "bindings", "keys"}, InstanceGuid: "i1"}
+		lookup := func(acteeGuid string) string {
+			return map[string]string{"b1": "i1", "k1": "i2"}[acteeGuid]
+		}
+		Expect(filter.Matches(guidTranslator.InstanceEvent{Type: "audit.service_binding.delete", InstanceGuid: "b1"}, lookup)).To(BeTrue())
+		Expect(filter.Matches(guidTranslator.InstanceEvent{Type: "audit.service_key.delete", InstanceGuid: "k1"}, lookup)).To(BeFalse())
+		Expect(EventInstanceGuid(guidTranslator.InstanceEvent{Type: "audit.service_key.delete", InstanceGuid: "k2"}, lookup)).To(Equal("k2"))
+		Expect(EventInstanceGuid(guidTranslator.InstanceEvent{Type: "audit.service_instance.delete", InstanceGuid: "b1"}, lookup)).To(Equal("b1"))
+	})
+})
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"errors"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+)
+
+// tokenRenewalMargin is how long before its expiry an access token is renewed, so that it does not expire during a request.
+const tokenRenewalMargin = time.Minute
+
+// eventToken is the access token for the cloud controller calls of the event commands. It is requested once with the
+// refresh token of the cf CLI and renewed only when it is about to expire or was rejected. A token without request
+// is fixed and never renewed.
+type eventToken struct {
+	accessToken string
+	expiry      time.Time
+	request     func() (string, error)
+}
+
+// newEventToken returns a token which is requested from the UAA of the targeted foundation when first used.
+func newEventToken() *eventToken {
+	return &eventToken{request: func() (string, error) {
+		return GetAccessToken(helper.GetLoginEndpoint(helper.ReadConfigJsonFile()), helper.GetRefreshToken(helper.ReadConfigJsonFile()), "refresh_token")
+	}}
+}
+
+// renewable reports whether a rejected token can be replaced by a new one.
+func (token *eventToken) renewable() bool {
+	return token.request != nil
+}
+
+// get returns the access token. A new token is requested if there is none yet, if the current one expires within
+// tokenRenewalMargin or if renew is set because the current one was rejected.
+func (token *eventToken) get(renew bool) (string, error) {
+	if !token.renewable() {
+		return token.accessToken, nil
+	}
+	if !renew && token.accessToken != "" && time.Now().Add(tokenRenewalMargin).Before(token.expiry) {
+		return token.accessToken, nil
+	}
+	accessToken, err := token.request()
+	if err != nil {
+		return "", err
+	}
+	if accessToken == "" {
+		return "", errors.New("no access token received from the UAA")
+	}
+	token.accessToken = accessToken
+	token.expiry = time.Unix(helper.NewTokenInfo("bearer "+accessToken).Expiry, 0)
+	return accessToken, nil
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("event token", func() {
+	jwt := func(expiry time.Time) string {
+		payload := base64.RawStdEncoding.EncodeToString([]byte(`{"exp":` + strconv.FormatInt(expiry.Unix(), 10) + `}`))
+		return "eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl"
+	}
+	newToken := func(expiry time.Time, requests *int) *eventToken {
+		return &eventToken{request: func() (string, error) {
+			*requests++
+			return jwt(expiry), nil
+		}}
+	}
+
+	It("A valid token should be requested once for all polls", func() {
+		var requests int
+		token := newToken(time.Now().Add(time.Hour), &requests)
+		for i := 0; i < 3; i++ {
+			accessToken, err := token.get(false)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(accessToken).NotTo(BeEmpty())
+		}
+		Expect(requests).To(Equal(1))
+	})
+	It("A token about to expire or rejected should be renewed", func() {
+		var requests int
+		token := newToken(time.Now().Add(30*time.Second), &requests)
+		token.get(false)
+		token.get(false)
+		Expect(requests).To(Equal(2))
+
+		requests = 0
+		token = newToken(time.Now().Add(time.Hour), &requests)
+		token.get(false)
+		token.get(true)
+		Expect(requests).To(Equal(2))
+	})
+	It("A fixed token should never be renewed", func() {
+		token := &eventToken{accessToken: "fixed"}
+		Expect(token.get(true)).To(Equal("fixed"))
+		Expect(token.renewable()).To(BeFalse())
+	})
+})
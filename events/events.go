@@ -1,11 +1,12 @@
 package events
 
 import (
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/SAP/service-fabrik-cli-plugin/helper"
 	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
 	"github.com/cloudfoundry/cli/plugin"
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
@@ -13,6 +14,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 )
@@ -27,8 +29,10 @@ type EventCommand struct {
 }
 
 func Initialize() {
-	httpClientDisabledSecurityCheck = CreateHttpClient(true)
 	httpClientEnabledSecurityCheck = CreateHttpClient(false)
+	if helper.SkipSslVerification() {
+		httpClientDisabledSecurityCheck = CreateHttpClient(true)
+	}
 }
 
 func NewEventsCommand(cliConnection plugin.CliConnection) *EventCommand {
@@ -42,36 +46,13 @@ func AddColor(text string, textColor color.Attribute) string {
 	return printer(text)
 }
 
-type Configuration struct {
-	ServiceBroker       string
-	ServiceBrokerExtUrl string
-	SkipSslFlag         bool
-}
-
-func GetConfiguration() Configuration {
-	var path string
-	var CF_HOME string = os.Getenv("CF_HOME")
-	if CF_HOME == "" {
-		CF_HOME = helper.GetHomeDir()
-	}
-	path = CF_HOME + "/.cf/conf.json"
-	file, _ := os.Open(path)
-	decoder := json.NewDecoder(file)
-	configuration := Configuration{}
-	err := decoder.Decode(&configuration)
-	if err != nil {
-		fmt.Println("error:", err)
-	}
-	return configuration
-}
-
 func CreateHttpClient(disableSecurityCheck bool) *http.Client {
 	client := &http.Client{
-		Transport: &http.Transport{
+		Transport: helper.NewTracingTransport(&http.Transport{
 			MaxIdleConnsPerHost: constants.MaxIdleConnections,
-			TLSClientConfig:     &tls.Config{InsecureSkipVerify: disableSecurityCheck},
+			TLSClientConfig:     helper.NewTLSConfig(disableSecurityCheck),
 			Proxy:           http.ProxyFromEnvironment,
-		},
+		}),
 		Timeout: time.Duration(constants.RequestTimeout) * time.Second,
 	}
 	return client
@@ -103,30 +84,48 @@ func CallHttpMethod(method string, url string, headers map[string]string, body i
 }
 
 func ExecuteCurl(apiUrl string, accessToken string, path string) ([]map[string]interface{}, error) {
+	var decodedBodyArray []map[string]interface{}
+	err := ExecuteCurlPages(apiUrl, accessToken, path, func(decodedBody map[string]interface{}) bool {
+		decodedBodyArray = append(decodedBodyArray, decodedBody)
+		return true
+	})
+	return decodedBodyArray, err
+}
+
+// ExecuteCurlPages calls the given cloud controller v2 list endpoint and passes every page to handlePage,
+// following next_url until the last page or until handlePage returns false.
+func ExecuteCurlPages(apiUrl string, accessToken string, path string, handlePage func(map[string]interface{}) bool) error {
+	return executeCurlPages(apiUrl, &eventToken{accessToken: accessToken}, path, handlePage)
+}
+
+// executeCurlPages is ExecuteCurlPages with a token which is renewed and the page requested again once if the cloud
+// controller rejects the token.
+func executeCurlPages(apiUrl string, token *eventToken, path string, handlePage func(map[string]interface{}) bool) error {
 	headers := make(map[string]string)
 	headers["Content-Type"] = "application/json"
 	headers["Accept"] = "application/json"
-	headers["Authorization"] = "bearer " + accessToken
-	var decodedBodyArray []map[string]interface{}
 	hasNextUrl := true
 	url := apiUrl + path
 	for hasNextUrl {
-		curlResponse, err := CallHttpMethod("GET", url, headers, nil, true)
-		defer curlResponse.Body.Close()
+		curlResponse, err := callWithToken(url, headers, token)
 		var decodedBody map[string]interface{}
 		if err != nil {
 			fmt.Printf("Error while CURL call")
-			return decodedBodyArray, err
+			return err
 		} else {
 			bodyBytes, err2 := ioutil.ReadAll(curlResponse.Body)
+			curlResponse.Body.Close()
 			if err2 != nil {
 				fmt.Printf("Error while decoding curl response")
-				return decodedBodyArray, err2
+				return err2
 			}
 
 			err = json.Unmarshal(bodyBytes, &decodedBody)
 			if err != nil {
-				return decodedBodyArray, err
+				return err
+			}
+			if description, flag := decodedBody["description"].(string); flag {
+				return errors.New(description)
 			}
 			nextUrl := decodedBody["next_url"]
 			if nextUrl != nil {
@@ -135,11 +134,28 @@ func ExecuteCurl(apiUrl string, accessToken string, path string) ([]map[string]i
 			} else {
 				hasNextUrl = false
 			}
-			decodedBodyArray = append(decodedBodyArray, decodedBody)
+			if !handlePage(decodedBody) {
+				hasNextUrl = false
+			}
+		}
+	}
+	return nil
+}
 
+// callWithToken gets the given url with the token, renewing a renewable token once if it is rejected.
+func callWithToken(url string, headers map[string]string, token *eventToken) (*http.Response, error) {
+	for renew := false; ; renew = true {
+		accessToken, err := token.get(renew)
+		if err != nil {
+			return nil, err
+		}
+		headers["Authorization"] = "bearer " + accessToken
+		curlResponse, err := CallHttpMethod("GET", url, headers, nil, helper.SkipSslVerification())
+		if err != nil || curlResponse.StatusCode != http.StatusUnauthorized || renew || !token.renewable() {
+			return curlResponse, err
 		}
+		curlResponse.Body.Close()
 	}
-	return decodedBodyArray, nil
 }
 
 func GetAccessToken(loginUrl string, refreshToken string, grantType string) (string, error) {
@@ -150,12 +166,12 @@ func GetAccessToken(loginUrl string, refreshToken string, grantType string) (str
 
 	data := "grant_type=" + grantType + "&client_id=cf&client_secret=&refresh_token=" + refreshToken
 
-	tokenResponse, err := CallHttpMethod("POST", loginUrl+"/oauth/token", headers, strings.NewReader(data), true)
-	defer tokenResponse.Body.Close()
+	tokenResponse, err := CallHttpMethod("POST", loginUrl+"/oauth/token", headers, strings.NewReader(data), helper.SkipSslVerification())
 	if err != nil {
 		fmt.Printf("Error while getting access-token (CF-Login-CURL call)")
 		return "", err
 	} else {
+		defer tokenResponse.Body.Close()
 		bodyBytes, err2 := ioutil.ReadAll(tokenResponse.Body)
 		if err2 != nil {
 			fmt.Printf("Error while decoding curl response")
@@ -168,11 +184,79 @@ func GetAccessToken(loginUrl string, refreshToken string, grantType string) (str
 	}
 }
 
-func (c *EventCommand) ListEvents(cliConnection plugin.CliConnection, noInstanceNames bool, action string) {
+// bindingInstances finds the service instance of bindings and keys by their create event, as the requests of their
+// delete events do not carry it. The instances are cached, also those not found, e.g. because the create event has
+// already expired in the cloud controller.
+type bindingInstances struct {
+	apiEndpoint string
+	token       *eventToken
+	instances   map[string]string
+}
+
+func newBindingInstances(token *eventToken) *bindingInstances {
+	return &bindingInstances{apiEndpoint: helper.GetApiEndpoint(helper.ReadConfigJsonFile()), token: token, instances: make(map[string]string)}
+}
+
+// instanceOf is an InstanceLookup.
+func (bindings *bindingInstances) instanceOf(acteeGuid string) string {
+	if instanceGuid, flag := bindings.instances[acteeGuid]; flag {
+		return instanceGuid
+	}
+	var instanceGuid string
+	err := executeCurlPages(bindings.apiEndpoint, bindings.token, "/v2/events?q=type+IN+audit.service_binding.create,audit.service_key.create%3Bactee:"+acteeGuid, func(page map[string]interface{}) bool {
+		resources, _ := page["resources"].([]interface{})
+		for _, resource := range resources {
+			resourceObj, _ := resource.(map[string]interface{})
+			instanceGuid = guidTranslator.StringField(guidTranslator.ToInstanceEvent(resourceObj).Request, "service_instance_guid")
+			if instanceGuid != "" {
+				return false
+			}
+		}
+		return true
+	})
+	if err == nil {
+		bindings.instances[acteeGuid] = instanceGuid
+	}
+	return instanceGuid
+}
+
+// FetchEvents returns the audit events of the space selected by the filter, oldest first. The token is reused across
+// calls and renewed only when needed, see eventToken. The instance of binding and key delete events is looked up through bindings.
+func FetchEvents(filter EventFilter, userSpaceGuid string, token *eventToken, bindings *bindingInstances) ([]guidTranslator.InstanceEvent, error) {
+	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
+
+	var matchingEvents []guidTranslator.InstanceEvent
+	err := executeCurlPages(apiEndpoint, token, filter.Query(userSpaceGuid), func(page map[string]interface{}) bool {
+		resources, _ := page["resources"].([]interface{})
+		for _, resource := range resources {
+			resourceObj, _ := resource.(map[string]interface{})
+			event := guidTranslator.ToInstanceEvent(resourceObj)
+			if !filter.Matches(event, bindings.instanceOf) {
+				continue
+			}
+			matchingEvents = append(matchingEvents, event)
+			if filter.Limit > 0 && len(matchingEvents) == filter.Limit {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit > 0 {
+		for left, right := 0, len(matchingEvents)-1; left < right; left, right = left+1, right-1 {
+			matchingEvents[left], matchingEvents[right] = matchingEvents[right], matchingEvents[left]
+		}
+	}
+	return matchingEvents, nil
+}
+
+// ListEvents lists the audit events of the space selected by the filter, oldest first. With details, the request
+// of every event, e.g. the parameters or the new plan of an update, is shown in an additional column.
+func (c *EventCommand) ListEvents(cliConnection plugin.CliConnection, noInstanceNames bool, filter EventFilter, details bool) {
 	Initialize()
 	fmt.Println("Getting the list of instance events in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
-	var cmd string
-	var actionType string
 	var userSpaceGuid = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
 
 	table := tablewriter.NewWriter(os.Stdout)
@@ -185,51 +269,88 @@ func (c *EventCommand) ListEvents(cliConnection plugin.CliConnection, noInstance
 	table.SetHeaderLine(false)
 	table.SetAutoFormatHeaders(false)
 
-	table.SetHeader([]string{AddColor("instance_name", constants.White), AddColor("instance_guid", constants.White), AddColor("event_type", constants.White), AddColor("user", constants.White), AddColor("created_at", constants.White)})
-
-	var columns int = 5
-	var field = make([]string, columns)
-
-	if action == "create" {
-		actionType = "audit.service_instance.create"
-		cmd = "/v2/events?q=type:" + actionType + "%3Bspace_guid:" + userSpaceGuid
-	} else if action == "update" {
-		actionType = "audit.service_instance.update"
-		cmd = "/v2/events?q=type:" + actionType + "%3Bspace_guid:" + userSpaceGuid
-	} else if action == "delete" {
-		actionType = "audit.service_instance.delete"
-		cmd = "/v2/events?q=type:" + actionType + "%3Bspace_guid:" + userSpaceGuid
-	} else {
-		cmd = "/v2/events?q=type+IN+audit.service_instance.delete,audit.service_instance.create,audit.service_instance.update%3Bspace_guid:" + userSpaceGuid
+	var header []string = []string{AddColor("instance_name", constants.White), AddColor("instance_guid", constants.White), AddColor("event_type", constants.White), AddColor("user", constants.White), AddColor("created_at", constants.White)}
+	if !filter.instanceIsActee() {
+		header[0] = AddColor("actee_name", constants.White)
+		header[1] = AddColor("actee_guid", constants.White)
 	}
-	var AuthorizationEndpoint string = helper.GetLoginEndpoint(helper.ReadConfigJsonFile())
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var refreshToken string = helper.GetRefreshToken(helper.ReadConfigJsonFile())
-
-	accessToken, _ := GetAccessToken(AuthorizationEndpoint, refreshToken, "refresh_token")
-	curlResponse, err := ExecuteCurl(apiEndpoint, accessToken, cmd)
+	if details {
+		header = append(header, AddColor("detail", constants.White))
+	}
+	table.SetHeader(header)
 
+	token := newEventToken()
+	matchingEvents, err := FetchEvents(filter, userSpaceGuid, token, newBindingInstances(token))
 	if err != nil {
 		fmt.Println(AddColor("FAILED", constants.Red))
 		fmt.Println("Errors in getting service instance events. ", err)
 		return
-	} else {
-		fmt.Println(AddColor("OK", constants.Green))
-		for _, val := range curlResponse {
-			resources := val["resources"].([]interface{})
-			for _, resource := range resources {
-				resourceObj := resource.(map[string]interface{})
-				resourceObjMetadata := resourceObj["metadata"].(map[string]interface{})
-				field[4] = resourceObjMetadata["created_at"].(string)
-				resourceObjEntity := resourceObj["entity"].(map[string]interface{})
-				field[1] = resourceObjEntity["actee"].(string)
-				field[2] = resourceObjEntity["type"].(string)
-				field[3] = resourceObjEntity["actor_name"].(string)
-				field[0] = resourceObjEntity["actee_name"].(string)
-				field[1] = AddColor(field[1], constants.Cyan)
-				table.Append(field)
-			}
+	}
+
+	fmt.Println(AddColor("OK", constants.Green))
+	planNames := make(map[string]string)
+	for _, event := range matchingEvents {
+		field := []string{event.InstanceName, AddColor(event.InstanceGuid, constants.Cyan), event.Type, event.Actor, event.Timestamp}
+		if details {
+			field = append(field, eventDetail(cliConnection, event, planNames))
 		}
+		table.Append(field)
 	}
 	table.Render()
 }
+
+// eventDetail describes the request of the event. A plan change is shown by the name of the new plan; plan names are cached in planNames.
+func eventDetail(cliConnection plugin.CliConnection, event guidTranslator.InstanceEvent, planNames map[string]string) string {
+	var detail string = requestDetail(event.Request)
+	planGuid := guidTranslator.StringField(event.Request, "service_plan_guid")
+	if planGuid == "" {
+		return detail
+	}
+	if _, flag := planNames[planGuid]; !flag {
+		if plan, err := guidTranslator.CurlObject(cliConnection, "/v2/service_plans/"+planGuid); err == nil {
+			planNames[planGuid] = guidTranslator.StringField(guidTranslator.Entity(plan), "name")
+		} else {
+			planNames[planGuid] = ""
+		}
+	}
+	if planNames[planGuid] == "" {
+		return detail
+	}
+	return "plan=" + planNames[planGuid] + " " + detail
+}
+
+// ResolveEventInstance returns the guid of the instance given to --instance, which may be the guid or the name of an
+// existing instance of the space, or any name a deleted instance had.
+func ResolveEventInstance(cliConnection plugin.CliConnection, userSpaceGuid string, value string) (string, error) {
+	instances, err := guidTranslator.FindSpaceInstances(cliConnection, userSpaceGuid)
+	if err != nil {
+		return "", err
+	}
+	for _, instance := range instances {
+		if instance.Guid == value || instance.Name == value {
+			return instance.Guid, nil
+		}
+	}
+
+	histories, err := guidTranslator.FindNameHistories(cliConnection, userSpaceGuid)
+	if err != nil {
+		return "", err
+	}
+	if _, flag := histories[value]; flag {
+		return value, nil
+	}
+	var guids []string
+	for guid, history := range histories {
+		if history.HasName(value) {
+			guids = append(guids, guid)
+		}
+	}
+	if len(guids) == 0 {
+		return "", errors.New("Service instance " + value + " not found in this space.")
+	}
+	if len(guids) > 1 {
+		sort.Strings(guids)
+		return "", errors.New(value + " maps to the instance guids " + strings.Join(guids, ", ") + ", please pass one of them to --instance.")
+	}
+	return guids[0], nil
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// eventCursor remembers how far the events have been followed. The cloud controller is asked for the events at or after
+// since, so the events at since which were already shown are remembered in seen.
+type eventCursor struct {
+	since time.Time
+	seen  map[string]bool
+}
+
+func newEventCursor(since time.Time) *eventCursor {
+	return &eventCursor{since: since, seen: make(map[string]bool)}
+}
+
+// advance returns the events which were not returned before, oldest first, and moves the cursor to the newest of them.
+func (cursor *eventCursor) advance(events []guidTranslator.InstanceEvent) []guidTranslator.InstanceEvent {
+	var newEvents []guidTranslator.InstanceEvent
+	for _, event := range events {
+		if !cursor.seen[event.Guid] {
+			newEvents = append(newEvents, event)
+		}
+	}
+	sort.SliceStable(newEvents, func(i, j int) bool {
+		timeI, _ := time.Parse(time.RFC3339, newEvents[i].Timestamp)
+		timeJ, _ := time.Parse(time.RFC3339, newEvents[j].Timestamp)
+		return timeI.Before(timeJ)
+	})
+
+	for _, event := range newEvents {
+		eventTime, err := time.Parse(time.RFC3339, event.Timestamp)
+		if err != nil {
+			cursor.seen[event.Guid] = true
+			continue
+		}
+		if eventTime.After(cursor.since) {
+			cursor.since = eventTime
+			cursor.seen = make(map[string]bool)
+		}
+		if !eventTime.Before(cursor.since) {
+			cursor.seen[event.Guid] = true
+		}
+	}
+	return newEvents
+}
+
+// followedEvent is the JSON representation of an event printed by FollowEvents.
+type followedEvent struct {
+	Timestamp    string                 `json:"timestamp"`
+	Type         string                 `json:"type"`
+	ActeeGuid    string                 `json:"actee_guid"`
+	ActeeName    string                 `json:"actee_name"`
+	InstanceGuid string                 `json:"instance_guid"`
+	User         string                 `json:"user"`
+	Request      map[string]interface{} `json:"request,omitempty"`
+}
+
+const followFormat = "%-20s  %-36s  %-36s  %-20s  %s\n"
+
+// FollowEvents prints the audit events selected by the filter as they happen, until interrupted. Events before the
+// start are skipped unless the filter has a since time. With jsonOutput every event is printed as one JSON object per line.
+func (c *EventCommand) FollowEvents(cliConnection plugin.CliConnection, filter EventFilter, interval time.Duration, jsonOutput bool) {
+	Initialize()
+	var userSpaceGuid = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	if !jsonOutput {
+		fmt.Println("Following the instance events in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "... (press Ctrl-C to stop)")
+		fmt.Printf(followFormat, "created_at", "actee_name", "actee_guid", "user", "event_type")
+	}
+
+	if filter.Since.IsZero() {
+		filter.Since = time.Now().UTC().Truncate(time.Second)
+	}
+	cursor := newEventCursor(filter.Since)
+	token := newEventToken()
+	bindings := newBindingInstances(token)
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupt)
+
+	for {
+		filter.Since = cursor.since
+		followedEvents, err := FetchEvents(filter, userSpaceGuid, token, bindings)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Errors in getting service instance events, retrying: ", err)
+		}
+		for _, event := range cursor.advance(followedEvents) {
+			if jsonOutput {
+				output, _ := json.Marshal(followedEvent{Timestamp: event.Timestamp, Type: event.Type, ActeeGuid: event.InstanceGuid, ActeeName: event.InstanceName, InstanceGuid: EventInstanceGuid(event, bindings.instanceOf), User: event.Actor, Request: event.Request})
+				fmt.Println(string(output))
+			} else {
+				fmt.Printf(followFormat, event.Timestamp, event.InstanceName, AddColor(event.InstanceGuid, constants.Cyan), event.Actor, event.Type)
+			}
+		}
+
+		select {
+		case <-interrupt:
+			if !jsonOutput {
+				fmt.Println("Stopped following the instance events.")
+			}
+			return
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package events
+
+import (
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("follow events", func() {
+	It("Events returned by several polls should be shown once", func() {
+		start, _ := time.Parse(time.RFC3339, "2018-11-01T00:00:00Z")
+		cursor := newEventCursor(start)
+
+		first := cursor.advance([]guidTranslator.InstanceEvent{
+			{Guid: "e2", Timestamp: "2018-11-01T00:00:05Z"},
+			{Guid: "e1", Timestamp: "2018-11-01T00:00:01Z"},
+		})
+		Expect(first).To(HaveLen(2))
+		Expect(first[0].Guid).To(Equal("e1"))
+		Expect(cursor.since.Format(time.RFC3339)).To(Equal("2018-11-01T00:00:05Z"))
+
+		second := cursor.advance([]guidTranslator.InstanceEvent{
+			{Guid: "e2", Timestamp: "2018-11-01T00:00:05Z"},
+			{Guid: "e3", Timestamp: "2018-11-01T00:00:05Z"},
+		})
+		Expect(second).To(HaveLen(1))
+		Expect(second[0].Guid).To(Equal("e3"))
+		Expect(cursor.advance([]guidTranslator.InstanceEvent{{Guid: "e3", Timestamp: "2018-11-01T00:00:05Z"}})).To(BeEmpty())
+	})
+})
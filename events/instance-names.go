@@ -0,0 +1,56 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// ShowInstanceNames shows all names the service instance with the given guid had, derived from its create, update and delete events.
+func (c *EventCommand) ShowInstanceNames(cliConnection plugin.CliConnection, instanceGuid string, jsonOutput bool) {
+	if !jsonOutput {
+		fmt.Println("Getting the name history of", AddColor(instanceGuid, constants.Cyan), "in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
+	}
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+
+	histories, err := guidTranslator.FindNameHistories(cliConnection, userSpaceGuid)
+	if err != nil {
+		errors.CfCliPluginError("/v2/events")
+	}
+	history, flag := histories[instanceGuid]
+	if !flag {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println("No create, update or delete events found for the instance guid " + instanceGuid + " in this space.")
+		fmt.Println("Enter 'cf backup' to check the list of commands and their usage.")
+		os.Exit(1)
+	}
+
+	if jsonOutput {
+		if history.Names == nil {
+			history.Names = []guidTranslator.NameChange{}
+		}
+		output, _ := json.MarshalIndent(history, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	fmt.Println(AddColor("OK", constants.Green))
+	table := backup.NewTable()
+	table.SetHeader([]string{AddColor("time", constants.White), AddColor("event", constants.White), AddColor("name", constants.White), AddColor("actor", constants.White)})
+	for _, change := range history.Names {
+		table.Append([]string{change.Timestamp, strings.TrimPrefix(change.Event, "audit.service_instance."), AddColor(change.Name, constants.Cyan), change.Actor})
+	}
+	table.Render()
+	if history.Deleted {
+		fmt.Println("The service instance has been deleted.")
+	}
+}
@@ -0,0 +1,181 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// TimelineEntry is one entry of the timeline of a service instance: an audit event, a backup or a restore.
+type TimelineEntry struct {
+	Time     string `json:"time"`
+	Kind     string `json:"kind"`
+	Actor    string `json:"actor"`
+	State    string `json:"state"`
+	Duration string `json:"duration"`
+	Detail   string `json:"detail"`
+}
+
+var instanceEventTypes = []string{"audit.service_instance.create", "audit.service_instance.update", "audit.service_instance.delete"}
+
+func requestDetail(request map[string]interface{}) string {
+	var keys []string
+	for key := range request {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		value, _ := json.Marshal(request[key])
+		parts = append(parts, key+"="+string(value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// BuildTimeline merges the audit events, the backups and the last restore of an instance into one list, oldest first.
+// Entries before since are left out unless since is the zero time. lastRestore may be nil.
+func BuildTimeline(instanceEvents []guidTranslator.InstanceEvent, records []backup.BackupRecord, lastRestore map[string]interface{}, since time.Time) []TimelineEntry {
+	var entries []TimelineEntry
+	for _, event := range instanceEvents {
+		entries = append(entries, TimelineEntry{
+			Time:     event.Timestamp,
+			Kind:     strings.TrimPrefix(event.Type, "audit."),
+			Actor:    event.Actor,
+			State:    "-",
+			Duration: "-",
+			Detail:   requestDetail(event.Request),
+		})
+	}
+	for _, record := range records {
+		entries = append(entries, TimelineEntry{
+			Time:     record.StartedAt,
+			Kind:     "backup",
+			Actor:    record.Username,
+			State:    record.State,
+			Duration: helper.FormatDuration(record.StartedAt, record.FinishedAt),
+			Detail:   "backup_guid=" + record.BackupGuid + " type=" + record.Type + " trigger=" + record.Trigger,
+		})
+	}
+	if lastRestore != nil {
+		startedAt, _ := lastRestore["started_at"].(string)
+		finishedAt, _ := lastRestore["finished_at"].(string)
+		username, _ := lastRestore["username"].(string)
+		state, _ := lastRestore["state"].(string)
+		backupGuid, _ := lastRestore["backup_guid"].(string)
+		entries = append(entries, TimelineEntry{
+			Time:     startedAt,
+			Kind:     "restore",
+			Actor:    username,
+			State:    state,
+			Duration: helper.FormatDuration(startedAt, finishedAt),
+			Detail:   "backup_guid=" + backupGuid,
+		})
+	}
+
+	var timeline []TimelineEntry
+	for _, entry := range entries {
+		entryTime, err := time.Parse(time.RFC3339, entry.Time)
+		if !since.IsZero() && err == nil && entryTime.Before(since) {
+			continue
+		}
+		timeline = append(timeline, entry)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		timeI, _ := time.Parse(time.RFC3339, timeline[i].Time)
+		timeJ, _ := time.Parse(time.RFC3339, timeline[j].Time)
+		return timeI.Before(timeJ)
+	})
+	return timeline
+}
+
+// findInstanceGuidForTimeline resolves the name to the guid of an existing instance of the space, or of a deleted one.
+func findInstanceGuidForTimeline(cliConnection plugin.CliConnection, userSpaceGuid string, serviceInstanceName string, records []backup.BackupRecord) string {
+	instances, err := guidTranslator.FindSpaceInstances(cliConnection, userSpaceGuid)
+	if err != nil {
+		errors.CfCliPluginError("/v2/spaces/" + userSpaceGuid + "/service_instances")
+	}
+	for _, instance := range instances {
+		if instance.Name == serviceInstanceName {
+			return instance.Guid
+		}
+	}
+	return backup.ResolveDeletedInstance(cliConnection, userSpaceGuid, serviceInstanceName, records, "")
+}
+
+// ShowInstanceTimeline shows the audit events, backups and last restore of the instance in chronological order.
+// The instance may also be a deleted one.
+func (c *EventCommand) ShowInstanceTimeline(cliConnection plugin.CliConnection, serviceInstanceName string, since time.Time, jsonOutput bool) {
+	if !jsonOutput {
+		fmt.Println("Getting the timeline of", AddColor(serviceInstanceName, constants.Cyan), "in the org", AddColor(helper.GetOrgName(helper.ReadConfigJsonFile()), constants.Cyan), "/ space", AddColor(helper.GetSpaceName(helper.ReadConfigJsonFile()), constants.Cyan), "...")
+	}
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	client := backup.GetHttpClient()
+
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+
+	records, err := backup.GetBackupRecords(client, userSpaceGuid, "")
+	if err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	var instanceGuid string = findInstanceGuidForTimeline(cliConnection, userSpaceGuid, serviceInstanceName, records)
+	if instanceGuid == "" {
+		errors.IncorrectInstanceName(serviceInstanceName)
+	}
+
+	spaceEvents, err := guidTranslator.FindInstanceEvents(cliConnection, userSpaceGuid, instanceEventTypes, since)
+	if err != nil {
+		errors.CfCliPluginError("/v2/events")
+	}
+	var instanceEvents []guidTranslator.InstanceEvent
+	for _, event := range spaceEvents {
+		if event.InstanceGuid == instanceGuid {
+			instanceEvents = append(instanceEvents, event)
+		}
+	}
+	var instanceRecords []backup.BackupRecord
+	for _, record := range records {
+		if record.InstanceGuid == instanceGuid {
+			instanceRecords = append(instanceRecords, record)
+		}
+	}
+	lastRestore, err := helper.GetRestoreStatus(client, instanceGuid, userSpaceGuid)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot get the last restore, showing the timeline without it:", err)
+	}
+
+	timeline := BuildTimeline(instanceEvents, instanceRecords, lastRestore, since)
+
+	if jsonOutput {
+		if timeline == nil {
+			timeline = []TimelineEntry{}
+		}
+		output, _ := json.MarshalIndent(map[string]interface{}{"instance_name": serviceInstanceName, "instance_guid": instanceGuid, "timeline": timeline}, "", "  ")
+		fmt.Println(string(output))
+		return
+	}
+
+	fmt.Println(AddColor("OK", constants.Green))
+	table := backup.NewTable()
+	table.SetHeader([]string{AddColor("time", constants.White), AddColor("kind", constants.White), AddColor("actor", constants.White), AddColor("state", constants.White), AddColor("duration", constants.White), AddColor("detail", constants.White)})
+	for _, entry := range timeline {
+		table.Append([]string{entry.Time, AddColor(entry.Kind, constants.Cyan), entry.Actor, entry.State, entry.Duration, entry.Detail})
+	}
+	table.Render()
+}
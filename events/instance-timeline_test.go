@@ -0,0 +1,44 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestEvents(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Events Suite")
+}
+
+var _ = Describe("instance timeline", func() {
+	instanceEvents := []guidTranslator.InstanceEvent{
+		{Type: "audit.service_instance.create", Actor: "admin", Timestamp: "2018-11-01T00:00:00Z"},
+		{Type: "audit.service_instance.update", Actor: "admin", Timestamp: "2018-11-15T00:00:00Z", Request: map[string]interface{}{"service_plan_guid": "plan-2"}},
+	}
+	records := []backup.BackupRecord{
+		{BackupGuid: "b1", Username: "admin", Type: "online", Trigger: "on-demand", State: "succeeded", StartedAt: "2018-11-10T00:00:00Z", FinishedAt: "2018-11-10T00:05:00Z"},
+	}
+	lastRestore := map[string]interface{}{"username": "admin", "state": "succeeded", "backup_guid": "b1", "started_at": "2018-11-20T00:00:00Z", "finished_at": "2018-11-20T00:01:30Z"}
+
+	It("Events, backups and restores should be merged in chronological order", func() {
+		timeline := BuildTimeline(instanceEvents, records, lastRestore, time.Time{})
+		Expect(timeline).To(HaveLen(4))
+		Expect(timeline[0].Kind).To(Equal("service_instance.create"))
+		Expect(timeline[1].Kind).To(Equal("backup"))
+		Expect(timeline[1].Duration).To(Equal("5m0s"))
+		Expect(timeline[2].Detail).To(Equal(`service_plan_guid="plan-2"`))
+		Expect(timeline[3].Kind).To(Equal("restore"))
+		Expect(timeline[3].Duration).To(Equal("1m30s"))
+	})
+	It("Entries before since should be left out", func() {
+		since, _ := time.Parse(time.RFC3339, "2018-11-12T00:00:00Z")
+		timeline := BuildTimeline(instanceEvents, records, nil, since)
+		Expect(timeline).To(HaveLen(1))
+		Expect(timeline[0].Kind).To(Equal("service_instance.update"))
+	})
+})
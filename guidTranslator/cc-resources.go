@@ -0,0 +1,351 @@
+package guidTranslator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+)
+
+// InstanceEvent is a service instance audit event of the cloud controller.
+type InstanceEvent struct {
+	Guid         string
+	Type         string
+	InstanceGuid string
+	InstanceName string
+	Actor        string
+	Timestamp    string
+	Request      map[string]interface{}
+}
+
+// CurlObject calls the given cloud controller endpoint through cf curl and decodes the JSON response.
+// Additional cf curl arguments, e.g. "-X", "POST", can be passed in curlArgs.
+func CurlObject(cliConnection plugin.CliConnection, cmd string, curlArgs ...string) (map[string]interface{}, error) {
+	output, err := cliConnection.CliCommandWithoutTerminalOutput(append([]string{"curl", cmd}, curlArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.Join(output, "\n")), &response); err != nil {
+		return nil, err
+	}
+	if description, flag := response["description"].(string); flag {
+		return nil, errors.New(description)
+	}
+	if errorList, flag := response["errors"].([]interface{}); flag && len(errorList) > 0 {
+		if detail, flag := errorList[0].(map[string]interface{})["detail"].(string); flag {
+			return nil, errors.New(detail)
+		}
+		return nil, errors.New("cloud controller request " + cmd + " failed")
+	}
+	return response, nil
+}
+
+// CurlResources calls the given cloud controller v2 list endpoint and returns the resources of all pages.
+func CurlResources(cliConnection plugin.CliConnection, cmd string) ([]map[string]interface{}, error) {
+	var resources []map[string]interface{}
+
+	for cmd != "" {
+		response, err := CurlObject(cliConnection, cmd)
+		if err != nil {
+			return nil, err
+		}
+		if pageResources, flag := response["resources"].([]interface{}); flag {
+			for _, resource := range pageResources {
+				resources = append(resources, resource.(map[string]interface{}))
+			}
+		}
+		cmd, _ = response["next_url"].(string)
+	}
+	return resources, nil
+}
+
+// Metadata and Entity return the metadata and entity sections of a cloud controller v2 resource.
+func Metadata(resource map[string]interface{}) map[string]interface{} {
+	metadata, _ := resource["metadata"].(map[string]interface{})
+	return metadata
+}
+
+func Entity(resource map[string]interface{}) map[string]interface{} {
+	entity, _ := resource["entity"].(map[string]interface{})
+	return entity
+}
+
+// StringField returns the string value of the given key, or "" if it is missing or not a string.
+func StringField(object map[string]interface{}, key string) string {
+	value, _ := object[key].(string)
+	return value
+}
+
+// ToInstanceEvent converts a cloud controller v2 event resource. For events of other resources than service instances,
+// InstanceGuid and InstanceName hold the guid and name of the actee.
+func ToInstanceEvent(resource map[string]interface{}) InstanceEvent {
+	entity := Entity(resource)
+	event := InstanceEvent{
+		Guid:         StringField(Metadata(resource), "guid"),
+		Type:         StringField(entity, "type"),
+		InstanceGuid: StringField(entity, "actee"),
+		InstanceName: StringField(entity, "actee_name"),
+		Actor:        StringField(entity, "actor_name"),
+		Timestamp:    StringField(entity, "timestamp"),
+	}
+	if metadata, flag := entity["metadata"].(map[string]interface{}); flag {
+		event.Request, _ = metadata["request"].(map[string]interface{})
+	}
+	return event
+}
+
+// FindInstanceEvents returns the service instance events of the given types in the space, oldest first.
+// If since is not the zero time, only events recorded after it are returned.
+func FindInstanceEvents(cliConnection plugin.CliConnection, userSpaceGuid string, eventTypes []string, since time.Time) ([]InstanceEvent, error) {
+	if userSpaceGuid == "" {
+		userSpaceGuid = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	}
+
+	var cmd string = "/v2/events?q=type+IN+" + strings.Join(eventTypes, ",") + "%3Bspace_guid:" + userSpaceGuid
+	if !since.IsZero() {
+		cmd = cmd + "%3Btimestamp" + url.QueryEscape(">"+since.UTC().Format(time.RFC3339))
+	}
+	resources, err := CurlResources(cliConnection, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []InstanceEvent
+	for _, resource := range resources {
+		events = append(events, ToInstanceEvent(resource))
+	}
+	return events, nil
+}
+
+// FindDeletedInstanceEvents returns the latest delete event of every service instance deleted in the space after since, keyed by instance guid.
+func FindDeletedInstanceEvents(cliConnection plugin.CliConnection, userSpaceGuid string, since time.Time) (map[string]InstanceEvent, error) {
+	events, err := FindInstanceEvents(cliConnection, userSpaceGuid, []string{"audit.service_instance.delete"}, since)
+	if err != nil {
+		return nil, err
+	}
+
+	deletedInstances := make(map[string]InstanceEvent)
+	for _, event := range events {
+		deletedInstances[event.InstanceGuid] = event
+	}
+	return deletedInstances, nil
+}
+
+// FindExistingInstanceGuids returns the guids of all service instances visible to the user.
+func FindExistingInstanceGuids(cliConnection plugin.CliConnection) (map[string]bool, error) {
+	resources, err := CurlResources(cliConnection, "/v2/service_instances")
+	if err != nil {
+		return nil, err
+	}
+
+	instanceGuids := make(map[string]bool)
+	for _, resource := range resources {
+		instanceGuids[StringField(Metadata(resource), "guid")] = true
+	}
+	return instanceGuids, nil
+}
+
+// FindSpaceGuid returns the guid of the space with the given name in the organization.
+func FindSpaceGuid(cliConnection plugin.CliConnection, orgGuid string, spaceName string) (string, error) {
+	resources, err := CurlResources(cliConnection, "/v2/organizations/"+orgGuid+"/spaces?q=name:"+url.QueryEscape(spaceName))
+	if err != nil {
+		return "", err
+	}
+	if len(resources) == 0 {
+		return "", errors.New("space " + spaceName + " not found")
+	}
+	return StringField(Metadata(resources[0]), "guid"), nil
+}
+
+// InstanceInfo describes a service instance together with its space and service.
+type InstanceInfo struct {
+	Guid         string
+	Name         string
+	SpaceGuid    string
+	SpaceName    string
+	OrgName      string
+	ServiceLabel string
+}
+
+// FindInstanceByGuid looks up a service instance, its space and its service with a single cloud controller request.
+func FindInstanceByGuid(cliConnection plugin.CliConnection, instanceGuid string) (InstanceInfo, error) {
+	response, err := CurlObject(cliConnection, "/v2/service_instances/"+instanceGuid+"?inline-relations-depth=2")
+	if err != nil {
+		return InstanceInfo{}, err
+	}
+	return toInstanceInfo(response), nil
+}
+
+// FindSpaceInstances returns the service instances of the space together with their services.
+func FindSpaceInstances(cliConnection plugin.CliConnection, userSpaceGuid string) ([]InstanceInfo, error) {
+	resources, err := CurlResources(cliConnection, "/v2/spaces/"+userSpaceGuid+"/service_instances?inline-relations-depth=2")
+	if err != nil {
+		return nil, err
+	}
+
+	var instances []InstanceInfo
+	for _, resource := range resources {
+		instances = append(instances, toInstanceInfo(resource))
+	}
+	return instances, nil
+}
+
+func toInstanceInfo(response map[string]interface{}) InstanceInfo {
+	entity := Entity(response)
+	info := InstanceInfo{
+		Guid:      StringField(Metadata(response), "guid"),
+		Name:      StringField(entity, "name"),
+		SpaceGuid: StringField(entity, "space_guid"),
+	}
+	if space, flag := entity["space"].(map[string]interface{}); flag {
+		info.SpaceName = StringField(Entity(space), "name")
+		if org, flag := Entity(space)["organization"].(map[string]interface{}); flag {
+			info.OrgName = StringField(Entity(org), "name")
+		}
+	}
+	if plan, flag := entity["service_plan"].(map[string]interface{}); flag {
+		if service, flag := Entity(plan)["service"].(map[string]interface{}); flag {
+			info.ServiceLabel = StringField(Entity(service), "label")
+		}
+	}
+	return info
+}
+
+// ResolveTargetSpace resolves the org and space given on the command line through the cloud controller. The space is
+// given either by spaceGuid, or by spaceName in the org orgName; without orgName the org targeted in config.json is used.
+func ResolveTargetSpace(cliConnection plugin.CliConnection, orgName string, spaceName string, spaceGuid string) (helper.TargetSpace, error) {
+	if spaceGuid != "" {
+		space, err := CurlObject(cliConnection, "/v2/spaces/"+spaceGuid+"?inline-relations-depth=1")
+		if err != nil {
+			return helper.TargetSpace{}, err
+		}
+		target := helper.TargetSpace{
+			OrgGuid:   StringField(Entity(space), "organization_guid"),
+			SpaceGuid: StringField(Metadata(space), "guid"),
+			SpaceName: StringField(Entity(space), "name"),
+		}
+		if org, flag := Entity(space)["organization"].(map[string]interface{}); flag {
+			target.OrgName = StringField(Entity(org), "name")
+		}
+		return target, nil
+	}
+
+	if spaceName == "" {
+		return helper.TargetSpace{}, errors.New("--org requires --space")
+	}
+	target := helper.TargetSpace{SpaceName: spaceName}
+	if orgName == "" {
+		target.OrgGuid = helper.GetOrgGUID(helper.ReadConfigJsonFile())
+		target.OrgName = helper.GetOrgName(helper.ReadConfigJsonFile())
+	} else {
+		orgs, err := CurlResources(cliConnection, "/v2/organizations?q=name:"+url.QueryEscape(orgName))
+		if err != nil {
+			return helper.TargetSpace{}, err
+		}
+		if len(orgs) == 0 {
+			return helper.TargetSpace{}, errors.New("org " + orgName + " not found")
+		}
+		target.OrgGuid = StringField(Metadata(orgs[0]), "guid")
+		target.OrgName = orgName
+	}
+
+	var err error
+	target.SpaceGuid, err = FindSpaceGuid(cliConnection, target.OrgGuid, spaceName)
+	return target, err
+}
+
+// SpaceInfo is a space together with its org.
+type SpaceInfo struct {
+	Guid    string
+	Name    string
+	OrgGuid string
+	OrgName string
+}
+
+// FindSharedSpaceGuids returns the guids of the spaces the service instance is shared with.
+func FindSharedSpaceGuids(cliConnection plugin.CliConnection, instanceGuid string) ([]string, error) {
+	response, err := CurlObject(cliConnection, "/v3/service_instances/"+instanceGuid+"/relationships/shared_spaces")
+	if err != nil {
+		return nil, err
+	}
+	return toSharedSpaceGuids(response), nil
+}
+
+// toSharedSpaceGuids extracts the space guids of a v3 shared_spaces relationship.
+func toSharedSpaceGuids(response map[string]interface{}) []string {
+	var spaceGuids []string
+	data, _ := response["data"].([]interface{})
+	for _, space := range data {
+		if spaceObj, flag := space.(map[string]interface{}); flag {
+			spaceGuids = append(spaceGuids, StringField(spaceObj, "guid"))
+		}
+	}
+	return spaceGuids
+}
+
+// FindSpaces returns the spaces of the given org visible to the user, or of all visible orgs if orgGuid is empty.
+// The org names are fetched with one separate list, as inline relations are limited to a few resources per page.
+func FindSpaces(cliConnection plugin.CliConnection, orgGuid string) ([]SpaceInfo, error) {
+	var cmd string = "/v2/spaces?results-per-page=100"
+	if orgGuid != "" {
+		cmd = "/v2/organizations/" + orgGuid + "/spaces?results-per-page=100"
+	}
+	resources, err := CurlResources(cliConnection, cmd)
+	if err != nil {
+		return nil, err
+	}
+	orgNames, err := FindOrgNames(cliConnection)
+	if err != nil {
+		return nil, err
+	}
+	return toSpaceInfos(resources, orgNames), nil
+}
+
+// FindOrgNames returns the names of all orgs visible to the user, keyed by org guid.
+func FindOrgNames(cliConnection plugin.CliConnection) (map[string]string, error) {
+	resources, err := CurlResources(cliConnection, "/v2/organizations?results-per-page=100")
+	if err != nil {
+		return nil, err
+	}
+
+	orgNames := make(map[string]string)
+	for _, resource := range resources {
+		orgNames[StringField(Metadata(resource), "guid")] = StringField(Entity(resource), "name")
+	}
+	return orgNames, nil
+}
+
+// toSpaceInfos converts cloud controller space resources, naming their orgs by orgNames.
+func toSpaceInfos(resources []map[string]interface{}, orgNames map[string]string) []SpaceInfo {
+	var spaces []SpaceInfo
+	for _, resource := range resources {
+		orgGuid := StringField(Entity(resource), "organization_guid")
+		spaces = append(spaces, SpaceInfo{
+			Guid:    StringField(Metadata(resource), "guid"),
+			Name:    StringField(Entity(resource), "name"),
+			OrgGuid: orgGuid,
+			OrgName: orgNames[orgGuid],
+		})
+	}
+	return spaces
+}
+
+// FindInstanceNames returns the names of all service instances visible to the user, keyed by instance guid.
+func FindInstanceNames(cliConnection plugin.CliConnection) (map[string]string, error) {
+	resources, err := CurlResources(cliConnection, "/v2/service_instances")
+	if err != nil {
+		return nil, err
+	}
+
+	instanceNames := make(map[string]string)
+	for _, resource := range resources {
+		instanceNames[StringField(Metadata(resource), "guid")] = StringField(Entity(resource), "name")
+	}
+	return instanceNames, nil
+}
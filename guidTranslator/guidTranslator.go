@@ -328,69 +328,6 @@ func FindServiceGUId(cliConnection plugin.CliConnection, servicePlanGuid string,
 	return "Invalid_Service_Guid"
 }
 
-func FindDeletedInstanceGuid(cliConnection plugin.CliConnection, instanceName string, output []string, userSpaceGuid string) map[string]string {
-	var cmd string
-	var err error
-
-	//Retrieve userSpaceGuid
-	if userSpaceGuid == "" {
-		userSpaceGuid = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
-	}
-
-	cmd = "/v2/events?q=type:audit.service_instance.delete%3Bspace_guid:" + userSpaceGuid
-
-	var flag int = 0
-	var guidTemp string
-	var instanceNameTemp string
-	var nextPage bool = false
-	guidInstanceMap := make(map[string]string)
-
-	var userInput string = "\"" + instanceName + "\""
-
-	for cmd != "null" {
-		if output == nil || nextPage == true {
-			output, err = cliConnection.CliCommandWithoutTerminalOutput("curl", cmd)
-		}
-
-		if err != nil {
-			errors.CfCliPluginError(cmd)
-		}
-
-		for index, val := range output {
-			_ = index
-			var str []string = strings.Fields(val)
-			if len(str) > 1 {
-
-				//extract guid of service-instance
-				if strings.Compare(str[0], "\"actee\":") == 0 {
-					guidTemp = str[1]
-				}
-
-				//extract service-instance-name
-				if strings.Compare(str[0], "\"actee_name\":") == 0 {
-					instanceNameTemp = str[1]
-				}
-
-				//Compare with userInput
-				if strings.Contains(instanceNameTemp, userInput) {
-					flag = 1
-					guidInstanceMap[guidTemp] = instanceNameTemp
-					instanceNameTemp = ""
-				}
-			}
-		}
-
-		cmd = findNextPage(output)
-		if cmd != "null" {
-			nextPage = true
-		}
-	}
-	if flag == 0 {
-		errors.InstanceGuidNotFound(instanceName)
-	}
-	return guidInstanceMap
-}
-
 func FindInstanceGuid(cliConnection plugin.CliConnection, instanceName string, output []string, userSpaceGuid string) string {
 	var cmd string
 	var err error
@@ -564,3 +501,54 @@ func FindServicePlanGuid(cliConnection plugin.CliConnection, instanceName string
 	errors.IncorrectSpace(helper.GetOrgName(helper.ReadConfigJsonFile()), helper.GetSpaceName(helper.ReadConfigJsonFile()))
 	return "Invalid Service_Plan_Guid"
 }
+
+// ResolveInstance returns the guid, name and space guid of the service instance given either by name in the targeted
+// space or, if inputGuidBool is true, by instance guid. A guid is checked with a single cloud controller lookup instead
+// of scanning all instances: the instance must be visible to the user, of a supported service and belong to, or be
+// shared with, the targeted space, so that a command never acts outside of it. Instances of other spaces are rejected
+// naming the space to target. The returned space guid is the one of the owning space, which the broker expects.
+func ResolveInstance(cliConnection plugin.CliConnection, serviceInstanceName string, instanceGuid string, inputGuidBool bool) (string, string, string) {
+	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	if inputGuidBool == false {
+		var guid string = FindInstanceGuid(cliConnection, serviceInstanceName, nil, userSpaceGuid)
+		return TrimGuid(guid), serviceInstanceName, userSpaceGuid
+	}
+
+	info, err := FindInstanceByGuid(cliConnection, instanceGuid)
+	if err != nil {
+		errors.IncorrectInstanceName(instanceGuid)
+	}
+	if !IsServiceNameValid(info.ServiceLabel) {
+		errors.IncorrectServiceType(info.Name, info.ServiceLabel)
+	}
+	var sharedSpaceGuids []string
+	if info.SpaceGuid != userSpaceGuid {
+		sharedSpaceGuids, _ = FindSharedSpaceGuids(cliConnection, info.Guid)
+	}
+	if !InTargetSpace(info, userSpaceGuid, sharedSpaceGuids) {
+		errors.InstanceInOtherSpace(info.Name, info.OrgName, info.SpaceName)
+	}
+	return info.Guid, info.Name, info.SpaceGuid
+}
+
+// TrimGuid removes the JSON quotes and the trailing comma FindInstanceGuid leaves around a guid.
+func TrimGuid(guid string) string {
+	return strings.Trim(strings.TrimRight(guid, ","), "\"")
+}
+
+// InTargetSpace reports whether the instance belongs to the targeted space or is shared with it, given the guids of
+// the spaces the instance is shared with.
+func InTargetSpace(info InstanceInfo, userSpaceGuid string, sharedSpaceGuids []string) bool {
+	if info.SpaceGuid == "" || userSpaceGuid == "" {
+		return false
+	}
+	if info.SpaceGuid == userSpaceGuid {
+		return true
+	}
+	for _, spaceGuid := range sharedSpaceGuids {
+		if spaceGuid == userSpaceGuid {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package guidTranslator
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -140,5 +141,61 @@ var _ = Describe("guidTranslator", func() {
 			})
 		})
 
+		Context("Resolve Instance", func() {
+			var response map[string]interface{}
+			json.Unmarshal([]byte(`{
+				"metadata": {"guid": "8912303d-3cdf-476e-b864-47f008b5ba5e"},
+				"entity": {
+					"name": "demo-blueprint",
+					"space_guid": "b0728cce-2eef-4a8b-ac57-b480f2c48461",
+					"space": {"entity": {"name": "dev", "organization": {"entity": {"name": "demo"}}}},
+					"service_plan": {"entity": {"service": {"entity": {"label": "blueprint"}}}}
+				}
+			}`), &response)
+
+			It("An instance looked up by guid should carry its space and service", func() {
+				Expect(toInstanceInfo(response)).To(Equal(InstanceInfo{
+					Guid:         "8912303d-3cdf-476e-b864-47f008b5ba5e",
+					Name:         "demo-blueprint",
+					SpaceGuid:    "b0728cce-2eef-4a8b-ac57-b480f2c48461",
+					SpaceName:    "dev",
+					OrgName:      "demo",
+					ServiceLabel: "blueprint",
+				}))
+			})
+			It("Only instances of the targeted space should be accepted", func() {
+				info := toInstanceInfo(response)
+				Expect(InTargetSpace(info, "b0728cce-2eef-4a8b-ac57-b480f2c48461", nil)).To(BeTrue())
+				Expect(InTargetSpace(info, "3bd5ad8c-0b47-43e8-8ec1-d0f4e04c1a5e", nil)).To(BeFalse())
+				Expect(InTargetSpace(InstanceInfo{}, "", nil)).To(BeFalse())
+			})
+			It("Instances shared with the targeted space should be accepted", func() {
+				var shared map[string]interface{}
+				json.Unmarshal([]byte(`{"data": [{"guid": "5d1b2cfa-6a3e-4b54-9e0b-3c6e1a5d2f10"}, {"guid": "3bd5ad8c-0b47-43e8-8ec1-d0f4e04c1a5e"}]}`), &shared)
+				sharedSpaceGuids := toSharedSpaceGuids(shared)
+				Expect(sharedSpaceGuids).To(Equal([]string{"5d1b2cfa-6a3e-4b54-9e0b-3c6e1a5d2f10", "3bd5ad8c-0b47-43e8-8ec1-d0f4e04c1a5e"}))
+
+				info := toInstanceInfo(response)
+				Expect(InTargetSpace(info, "3bd5ad8c-0b47-43e8-8ec1-d0f4e04c1a5e", sharedSpaceGuids)).To(BeTrue())
+				Expect(InTargetSpace(info, "9c0a7f7e-1f4b-4c1e-8a55-6f7f2b3d4e5a", sharedSpaceGuids)).To(BeFalse())
+				Expect(info.SpaceGuid).To(Equal("b0728cce-2eef-4a8b-ac57-b480f2c48461"))
+			})
+			It("The guid of an instance looked up by name should be unquoted", func() {
+				Expect(TrimGuid("\"8912303d-3cdf-476e-b864-47f008b5ba5e\",")).To(Equal("8912303d-3cdf-476e-b864-47f008b5ba5e"))
+				Expect(TrimGuid("8912303d-3cdf-476e-b864-47f008b5ba5e")).To(Equal("8912303d-3cdf-476e-b864-47f008b5ba5e"))
+			})
+		})
+		Context("Spaces", func() {
+			It("The org of each space should be named from the separate org list", func() {
+				resources := []map[string]interface{}{
+					{"metadata": map[string]interface{}{"guid": "s1"}, "entity": map[string]interface{}{"name": "dev", "organization_guid": "o1"}},
+					{"metadata": map[string]interface{}{"guid": "s2"}, "entity": map[string]interface{}{"name": "live", "organization_guid": "o2"}},
+				}
+				Expect(toSpaceInfos(resources, map[string]string{"o1": "demo", "o2": "prod"})).To(Equal([]SpaceInfo{
+					{Guid: "s1", Name: "dev", OrgGuid: "o1", OrgName: "demo"},
+					{Guid: "s2", Name: "live", OrgGuid: "o2", OrgName: "prod"},
+				}))
+			})
+		})
 	})
 })
@@ -0,0 +1,99 @@
+package guidTranslator
+
+import (
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// NameChange is one entry of the name history of a service instance: the name it got through the given event.
+type NameChange struct {
+	Name      string `json:"name"`
+	Event     string `json:"event"`
+	Actor     string `json:"actor"`
+	Timestamp string `json:"timestamp"`
+}
+
+// NameHistory is the name history of a service instance, oldest name first.
+type NameHistory struct {
+	InstanceGuid string       `json:"instance_guid"`
+	Names        []NameChange `json:"names"`
+	Deleted      bool         `json:"deleted"`
+}
+
+// HasName reports whether the instance had the given name at any time.
+func (history NameHistory) HasName(name string) bool {
+	for _, change := range history.Names {
+		if change.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentName returns the last known name of the instance.
+func (history NameHistory) CurrentName() string {
+	if len(history.Names) == 0 {
+		return ""
+	}
+	return history.Names[len(history.Names)-1].Name
+}
+
+// BuildNameHistories derives the name history of every instance from its create, update and delete events. The name of
+// an update event is taken from the request metadata, which holds the new name if the instance was renamed.
+func BuildNameHistories(events []InstanceEvent) map[string]*NameHistory {
+	sorted := append([]InstanceEvent(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		timeI, _ := time.Parse(time.RFC3339, sorted[i].Timestamp)
+		timeJ, _ := time.Parse(time.RFC3339, sorted[j].Timestamp)
+		return timeI.Before(timeJ)
+	})
+
+	histories := make(map[string]*NameHistory)
+	for _, event := range sorted {
+		history, flag := histories[event.InstanceGuid]
+		if !flag {
+			history = &NameHistory{InstanceGuid: event.InstanceGuid}
+			histories[event.InstanceGuid] = history
+		}
+
+		var name string = StringField(event.Request, "name")
+		if name == "" {
+			name = event.InstanceName
+		}
+		if event.Type == "audit.service_instance.delete" {
+			history.Deleted = true
+		}
+		if name != "" && name != history.CurrentName() {
+			history.Names = append(history.Names, NameChange{Name: name, Event: event.Type, Actor: event.Actor, Timestamp: event.Timestamp})
+		}
+	}
+	return histories
+}
+
+// FindNameHistories returns the name histories of the service instances of the space, keyed by instance guid.
+func FindNameHistories(cliConnection plugin.CliConnection, userSpaceGuid string) (map[string]*NameHistory, error) {
+	events, err := FindInstanceEvents(cliConnection, userSpaceGuid, []string{"audit.service_instance.create", "audit.service_instance.update", "audit.service_instance.delete"}, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return BuildNameHistories(events), nil
+}
+
+// FindDeletedInstanceGuidsByName returns the guids of the deleted instances of the space which had the given name at any time.
+func FindDeletedInstanceGuidsByName(cliConnection plugin.CliConnection, userSpaceGuid string, instanceName string) ([]string, error) {
+	histories, err := FindNameHistories(cliConnection, userSpaceGuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var guids []string
+	for guid, history := range histories {
+		if history.Deleted && history.HasName(instanceName) {
+			guids = append(guids, guid)
+		}
+	}
+	sort.Strings(guids)
+	return guids, nil
+}
@@ -0,0 +1,32 @@
+package guidTranslator
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("name history", func() {
+	events := []InstanceEvent{
+		{InstanceGuid: "i1", InstanceName: "old-name", Type: "audit.service_instance.delete", Actor: "carol", Timestamp: "2018-11-30T00:00:00Z"},
+		{InstanceGuid: "i1", InstanceName: "blueprint", Type: "audit.service_instance.create", Actor: "alice", Timestamp: "2018-11-01T00:00:00Z", Request: map[string]interface{}{"name": "blueprint"}},
+		{InstanceGuid: "i1", InstanceName: "blueprint", Type: "audit.service_instance.update", Actor: "bob", Timestamp: "2018-11-10T00:00:00Z", Request: map[string]interface{}{"name": "old-name"}},
+		{InstanceGuid: "i1", InstanceName: "old-name", Type: "audit.service_instance.update", Actor: "bob", Timestamp: "2018-11-20T00:00:00Z", Request: map[string]interface{}{"parameters": map[string]interface{}{}}},
+		{InstanceGuid: "i2", InstanceName: "blueprint", Type: "audit.service_instance.create", Actor: "alice", Timestamp: "2018-12-01T00:00:00Z", Request: map[string]interface{}{"name": "blueprint"}},
+	}
+
+	It("Renames should be taken from the request of update events, oldest name first", func() {
+		history := BuildNameHistories(events)["i1"]
+		Expect(history.Deleted).To(BeTrue())
+		Expect(history.Names).To(HaveLen(2))
+		Expect(history.Names[0].Name).To(Equal("blueprint"))
+		Expect(history.Names[1].Name).To(Equal("old-name"))
+		Expect(history.Names[1].Actor).To(Equal("bob"))
+		Expect(history.CurrentName()).To(Equal("old-name"))
+	})
+	It("An instance should match all its historical names", func() {
+		histories := BuildNameHistories(events)
+		Expect(histories["i1"].HasName("blueprint")).To(BeTrue())
+		Expect(histories["i2"].HasName("old-name")).To(BeFalse())
+		Expect(histories["i2"].Deleted).To(BeFalse())
+	})
+})
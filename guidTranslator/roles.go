@@ -0,0 +1,58 @@
+package guidTranslator
+
+import (
+	"sort"
+
+	"code.cloudfoundry.org/cli/plugin"
+)
+
+// roleNames maps the cloud controller v3 role types to the role names shown by the cf CLI.
+var roleNames = map[string]string{
+	"organization_user":            "OrgUser",
+	"organization_auditor":         "OrgAuditor",
+	"organization_manager":         "OrgManager",
+	"organization_billing_manager": "BillingManager",
+	"space_auditor":                "SpaceAuditor",
+	"space_developer":              "SpaceDeveloper",
+	"space_manager":                "SpaceManager",
+	"space_supporter":              "SpaceSupporter",
+}
+
+// RoleName returns the name the cf CLI uses for a cloud controller role type, e.g. SpaceDeveloper for space_developer.
+func RoleName(roleType string) string {
+	if name, flag := roleNames[roleType]; flag {
+		return name
+	}
+	return roleType
+}
+
+// findRoleTypes returns the sorted role types of the v3 roles matching the filter query.
+func findRoleTypes(cliConnection plugin.CliConnection, query string) ([]string, error) {
+	response, err := CurlObject(cliConnection, "/v3/roles?"+query+"&per_page=5000")
+	if err != nil {
+		return nil, err
+	}
+	var types []string
+	if resources, flag := response["resources"].([]interface{}); flag {
+		for _, resource := range resources {
+			if roleType := StringField(resource.(map[string]interface{}), "type"); roleType != "" {
+				types = append(types, roleType)
+			}
+		}
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// FindUserRoles returns the role types of the user in the org and in the space, looked up with the cloud controller v3 API.
+func FindUserRoles(cliConnection plugin.CliConnection, userGuid string, orgGuid string, spaceGuid string) ([]string, []string, error) {
+	orgRoles, err := findRoleTypes(cliConnection, "user_guids="+userGuid+"&organization_guids="+orgGuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	spaceRoles, err := findRoleTypes(cliConnection, "user_guids="+userGuid+"&space_guids="+spaceGuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return orgRoles, spaceRoles, nil
+}
@@ -0,0 +1,97 @@
+package helper
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseArguments splits the arguments following the command name into positional arguments and flags.
+// Flags listed in valueFlags consume the next argument as their value, flags listed in boolFlags are set to "true".
+// Flags are keyed by their full name, e.g. "--confirm".
+func ParseArguments(args []string, valueFlags []string, boolFlags []string) ([]string, map[string]string, error) {
+	var positional []string
+	flags := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+		if contains(boolFlags, arg) {
+			flags[arg] = "true"
+			continue
+		}
+		if !contains(valueFlags, arg) {
+			return nil, nil, errors.New("unknown flag " + arg)
+		}
+		if i+1 >= len(args) || strings.HasPrefix(args[i+1], "--") {
+			return nil, nil, errors.New("flag " + arg + " requires a value")
+		}
+		flags[arg] = args[i+1]
+		i++
+	}
+	return positional, flags, nil
+}
+
+// ExtractFlags removes the given value flags together with their values from args, wherever they appear, and returns
+// the remaining arguments and the extracted flags. It is used for flags which are accepted by every command.
+func ExtractFlags(args []string, valueFlags []string) ([]string, map[string]string, error) {
+	var remaining []string
+	flags := make(map[string]string)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !contains(valueFlags, arg) {
+			remaining = append(remaining, arg)
+			continue
+		}
+		if i+1 >= len(args) || strings.HasPrefix(args[i+1], "--") {
+			return nil, nil, errors.New("flag " + arg + " requires a value")
+		}
+		flags[arg] = args[i+1]
+		i++
+	}
+	return remaining, flags, nil
+}
+
+// ParseDuration extends time.ParseDuration with the units "d" (days) and "w" (weeks), e.g. "30d" or "2w".
+func ParseDuration(value string) (time.Duration, error) {
+	var unit time.Duration
+	if strings.HasSuffix(value, "d") {
+		unit = 24 * time.Hour
+	} else if strings.HasSuffix(value, "w") {
+		unit = 7 * 24 * time.Hour
+	} else {
+		return time.ParseDuration(value)
+	}
+	count, err := strconv.Atoi(strings.TrimRight(value, "dw"))
+	if err != nil || count < 0 {
+		return 0, errors.New("invalid duration " + value)
+	}
+	return time.Duration(count) * unit, nil
+}
+
+// ParseSince turns the value of a --since flag into a point in time. The value is either a duration relative to now,
+// e.g. "7d", or an RFC3339 timestamp.
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if duration, err := ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("invalid time " + value + ", expected a duration like 7d or a timestamp like 2018-11-12T11:45:26Z")
+	}
+	return since, nil
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package helper
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("argument parser", func() {
+	Context("Parsing arguments", func() {
+		It("Positional arguments and flags should be separated", func() {
+			positional, flags, err := ParseArguments([]string{"demo-blueprint", "--keep-last", "3", "--confirm"}, []string{"--keep-last"}, []string{"--confirm"})
+			Expect(err).To(BeNil())
+			Expect(positional).To(Equal([]string{"demo-blueprint"}))
+			Expect(flags["--keep-last"]).To(Equal("3"))
+			Expect(flags["--confirm"]).To(Equal("true"))
+		})
+		It("Unknown flags should be rejected", func() {
+			_, _, err := ParseArguments([]string{"--unknown"}, []string{"--keep-last"}, nil)
+			Expect(err).NotTo(BeNil())
+		})
+		It("Value flags without a value should be rejected", func() {
+			_, _, err := ParseArguments([]string{"--keep-last", "--confirm"}, []string{"--keep-last"}, []string{"--confirm"})
+			Expect(err).NotTo(BeNil())
+		})
+	})
+	Context("Extracting flags", func() {
+		It("Extracted flags should be removed wherever they appear", func() {
+			remaining, flags, err := ExtractFlags([]string{"list-backup", "--space", "dev", "demo-blueprint", "--org", "acme"}, []string{"--org", "--space"})
+			Expect(err).To(BeNil())
+			Expect(remaining).To(Equal([]string{"list-backup", "demo-blueprint"}))
+			Expect(flags["--space"]).To(Equal("dev"))
+			Expect(flags["--org"]).To(Equal("acme"))
+		})
+		It("Extracted flags without a value should be rejected", func() {
+			_, _, err := ExtractFlags([]string{"list-backup", "--space"}, []string{"--space"})
+			Expect(err).NotTo(BeNil())
+		})
+	})
+	Context("Parsing durations", func() {
+		It("Days and weeks should be supported", func() {
+			duration, err := ParseDuration("30d")
+			Expect(err).To(BeNil())
+			Expect(duration).To(Equal(30 * 24 * time.Hour))
+			duration, err = ParseDuration("2w")
+			Expect(err).To(BeNil())
+			Expect(duration).To(Equal(14 * 24 * time.Hour))
+		})
+		It("Standard durations should be supported", func() {
+			duration, err := ParseDuration("36h")
+			Expect(err).To(BeNil())
+			Expect(duration).To(Equal(36 * time.Hour))
+		})
+		It("Invalid durations should be rejected", func() {
+			_, err := ParseDuration("xd")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+	Context("Parsing since values", func() {
+		It("Durations should be relative to now", func() {
+			now, _ := time.Parse(time.RFC3339, "2018-11-30T00:00:00Z")
+			since, err := ParseSince("7d", now)
+			Expect(err).To(BeNil())
+			Expect(since.Format(time.RFC3339)).To(Equal("2018-11-23T00:00:00Z"))
+		})
+		It("Timestamps should be accepted", func() {
+			since, err := ParseSince("2018-11-12T11:45:26Z", time.Now())
+			Expect(err).To(BeNil())
+			Expect(since.Format(time.RFC3339)).To(Equal("2018-11-12T11:45:26Z"))
+		})
+	})
+})
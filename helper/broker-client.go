@@ -0,0 +1,61 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// CallBroker sends an authorized JSON request to the service broker and returns the response together with its body.
+func CallBroker(client *http.Client, method string, url string, body io.Reader) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", GetAccessToken(ReadConfigJsonFile()))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	return resp, respBody, err
+}
+
+// BrokerError turns an unsuccessful broker response into an error carrying the broker's description.
+func BrokerError(resp *http.Response, body []byte) error {
+	var response map[string]interface{}
+	if err := json.Unmarshal(body, &response); err == nil {
+		if description, flag := response["description"].(string); flag {
+			return fmt.Errorf("%s: %s", resp.Status, description)
+		}
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// GetRestoreStatus returns the last restore operation of the given instance in the given space, or nil if the instance
+// was never restored.
+func GetRestoreStatus(client *http.Client, instanceGuid string, spaceGuid string) (map[string]interface{}, error) {
+	resp, body, err := CallBroker(client, "GET", GetBrokerApiUrl()+"/service_instances/"+instanceGuid+"/restore?space_guid="+spaceGuid, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, BrokerError(resp, body)
+	}
+
+	var status map[string]interface{}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
@@ -0,0 +1,114 @@
+package helper
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	discoveredBrokerUrls     = make(map[string]string)
+	discoveredBrokerUrlsLock sync.Mutex
+)
+
+// GetBrokerApiUrl returns the URL of the Service Fabrik broker API, including serviceBrokerExtUrl. The broker is taken from
+// serviceBrokerUrl in conf.json, else from the registration of the broker named serviceBroker in the cloud controller, and
+// only as a last resort by substituting the broker name for "api" in the hostname of the API endpoint.
+func GetBrokerApiUrl() string {
+	brokerApiUrl, _ := ResolveBrokerApiUrl()
+	return brokerApiUrl
+}
+
+// ResolveBrokerApiUrl returns the URL of the broker API as described for GetBrokerApiUrl, together with the way it was found.
+func ResolveBrokerApiUrl() (string, string) {
+	configuration := GetConfiguration()
+	if configuration.ServiceBrokerUrl != "" {
+		return strings.TrimRight(configuration.ServiceBrokerUrl, "/") + configuration.ServiceBrokerExtUrl, "serviceBrokerUrl"
+	}
+
+	var apiEndpoint string = GetApiEndpoint(ReadConfigJsonFile())
+	if brokerUrl, err := discoverBrokerUrl(apiEndpoint, configuration.ServiceBroker); err == nil {
+		return brokerUrl + configuration.ServiceBrokerExtUrl, "broker registration"
+	}
+	return SubstituteBrokerHost(apiEndpoint, configuration.ServiceBroker) + configuration.ServiceBrokerExtUrl, "hostname substitution"
+}
+
+// discoverBrokerUrl looks up the broker registered under the given name. The result is cached for the API endpoint.
+func discoverBrokerUrl(apiEndpoint string, brokerName string) (string, error) {
+	discoveredBrokerUrlsLock.Lock()
+	defer discoveredBrokerUrlsLock.Unlock()
+	if brokerUrl, flag := discoveredBrokerUrls[apiEndpoint+" "+brokerName]; flag {
+		return brokerUrl, nil
+	}
+
+	req, err := http.NewRequest("GET", apiEndpoint+"/v2/service_brokers?q=name:"+url.QueryEscape(brokerName), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", GetAccessToken(ReadConfigJsonFile()))
+	req.Header.Set("Accept", "application/json")
+	resp, err := NewHttpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("listing the service brokers failed with " + resp.Status)
+	}
+
+	var response struct {
+		Resources []struct {
+			Entity struct {
+				BrokerUrl string `json:"broker_url"`
+			} `json:"entity"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", err
+	}
+	if len(response.Resources) == 0 {
+		return "", errors.New("service broker " + brokerName + " not found")
+	}
+	brokerUrl, err := BrokerBaseUrl(response.Resources[0].Entity.BrokerUrl)
+	if err != nil {
+		return "", err
+	}
+	discoveredBrokerUrls[apiEndpoint+" "+brokerName] = brokerUrl
+	return brokerUrl, nil
+}
+
+// BrokerBaseUrl returns the scheme and host of the registered broker URL, to which serviceBrokerExtUrl is appended;
+// the path of the registration is the one of the service broker API.
+func BrokerBaseUrl(registeredUrl string) (string, error) {
+	parsed, err := url.Parse(registeredUrl)
+	if err != nil {
+		return "", err
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return "", errors.New("invalid broker url " + registeredUrl)
+	}
+	return parsed.Scheme + "://" + parsed.Host, nil
+}
+
+// SubstituteBrokerHost derives the broker URL from the API endpoint by replacing the first label "api" of its hostname
+// with the broker name. If the hostname does not start with "api", the first "api" in it is replaced.
+func SubstituteBrokerHost(apiEndpoint string, brokerName string) string {
+	parsed, err := url.Parse(apiEndpoint)
+	if err != nil || parsed.Host == "" {
+		return strings.Replace(apiEndpoint, "api", brokerName, 1)
+	}
+	if strings.HasPrefix(parsed.Host, "api.") {
+		parsed.Host = brokerName + strings.TrimPrefix(parsed.Host, "api")
+	} else {
+		parsed.Host = strings.Replace(parsed.Host, "api", brokerName, 1)
+	}
+	return strings.TrimRight(parsed.String(), "/")
+}
@@ -0,0 +1,20 @@
+package helper
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("broker url", func() {
+	It("Only the api label of the hostname should be substituted", func() {
+		Expect(SubstituteBrokerHost("https://api.myapi.example.com", "service-fabrik-broker")).To(Equal("https://service-fabrik-broker.myapi.example.com"))
+		Expect(SubstituteBrokerHost("https://api.cf.example.com/", "service-fabrik-broker")).To(Equal("https://service-fabrik-broker.cf.example.com"))
+	})
+	It("The path of a registered broker url should be dropped", func() {
+		brokerUrl, err := BrokerBaseUrl("https://service-fabrik-broker.cf.example.com/cf")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(brokerUrl).To(Equal("https://service-fabrik-broker.cf.example.com"))
+		_, err = BrokerBaseUrl("service-fabrik-broker")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,217 @@
+package helper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfKeys are the settings of a profile in conf.json, in the order in which they are listed.
+var ConfKeys = []string{"serviceBroker", "serviceBrokerExtUrl", "serviceBrokerUrl", "skipSslFlag", "caCertFile", "clientCertFile", "clientKeyFile", "maxRetries", "retryDelay"}
+
+// confEnvironment maps the environment variables which override settings of conf.json to the settings.
+var confEnvironment = map[string]string{
+	"SF_BROKER_NAME": "serviceBroker",
+	"SF_EXT_URL":     "serviceBrokerExtUrl",
+	"SF_BROKER_URL":  "serviceBrokerUrl",
+	"SF_SKIP_SSL":    "skipSslFlag",
+	"SF_MAX_RETRIES": "maxRetries",
+	"SF_RETRY_DELAY": "retryDelay",
+}
+
+// ConfEnvironmentVariable returns the environment variable overriding the setting, or "" if there is none.
+func ConfEnvironmentVariable(key string) string {
+	for variable, setting := range confEnvironment {
+		if setting == key {
+			return variable
+		}
+	}
+	return ""
+}
+
+func unknownKey(key string) error {
+	return errors.New("unknown setting " + key + ", expected one of " + strings.Join(ConfKeys, ", "))
+}
+
+// Get returns the value of the setting as a string.
+func (configuration Configuration) Get(key string) (string, error) {
+	switch key {
+	case "serviceBroker":
+		return configuration.ServiceBroker, nil
+	case "serviceBrokerExtUrl":
+		return configuration.ServiceBrokerExtUrl, nil
+	case "serviceBrokerUrl":
+		return configuration.ServiceBrokerUrl, nil
+	case "skipSslFlag":
+		return strconv.FormatBool(configuration.SkipSslFlag), nil
+	case "caCertFile":
+		return configuration.CaCertFile, nil
+	case "clientCertFile":
+		return configuration.ClientCertFile, nil
+	case "clientKeyFile":
+		return configuration.ClientKeyFile, nil
+	case "maxRetries":
+		if configuration.MaxRetries == nil {
+			return "", nil
+		}
+		return strconv.Itoa(*configuration.MaxRetries), nil
+	case "retryDelay":
+		return configuration.RetryDelay, nil
+	}
+	return "", unknownKey(key)
+}
+
+// Set validates the value and stores it in the setting.
+func (configuration *Configuration) Set(key string, value string) error {
+	if err := validateSetting(key, value); err != nil {
+		return err
+	}
+	switch key {
+	case "serviceBroker":
+		configuration.ServiceBroker = value
+	case "serviceBrokerExtUrl":
+		configuration.ServiceBrokerExtUrl = value
+	case "serviceBrokerUrl":
+		configuration.ServiceBrokerUrl = value
+	case "skipSslFlag":
+		configuration.SkipSslFlag, _ = strconv.ParseBool(value)
+	case "caCertFile":
+		configuration.CaCertFile = value
+	case "clientCertFile":
+		configuration.ClientCertFile = value
+	case "clientKeyFile":
+		configuration.ClientKeyFile = value
+	case "maxRetries":
+		configuration.MaxRetries = nil
+		if value != "" {
+			maxRetries, _ := strconv.Atoi(value)
+			configuration.MaxRetries = &maxRetries
+		}
+	case "retryDelay":
+		configuration.RetryDelay = value
+	}
+	return nil
+}
+
+// Unset resets the setting to its default value.
+func (configuration *Configuration) Unset(key string) error {
+	value, err := DefaultConfiguration().Get(key)
+	if err != nil {
+		return err
+	}
+	return configuration.Set(key, value)
+}
+
+// validateSetting checks a single value against the schema of the setting. Empty values are allowed for optional settings.
+func validateSetting(key string, value string) error {
+	switch key {
+	case "serviceBroker":
+		if value == "" || strings.ContainsAny(value, "/: ") {
+			return errors.New("serviceBroker must be a broker name without '/', ':' or blanks")
+		}
+	case "serviceBrokerExtUrl":
+		if value != "" && !strings.HasPrefix(value, "/") {
+			return errors.New("serviceBrokerExtUrl must be a path starting with '/'")
+		}
+	case "serviceBrokerUrl":
+		if value == "" {
+			return nil
+		}
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return errors.New("serviceBrokerUrl must be an http or https URL, e.g. https://service-fabrik-broker.cf.example.com")
+		}
+	case "skipSslFlag":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.New("skipSslFlag must be true or false")
+		}
+	case "caCertFile", "clientCertFile", "clientKeyFile":
+		if value == "" {
+			return nil
+		}
+		if _, err := os.Stat(value); err != nil {
+			return errors.New(key + " must be an existing file: " + err.Error())
+		}
+	case "maxRetries":
+		if value == "" {
+			return nil
+		}
+		if maxRetries, err := strconv.Atoi(value); err != nil || maxRetries < 0 || maxRetries > 10 {
+			return errors.New("maxRetries must be a number from 0 to 10")
+		}
+	case "retryDelay":
+		if value == "" {
+			return nil
+		}
+		if delay, err := time.ParseDuration(value); err != nil || delay <= 0 {
+			return errors.New("retryDelay must be a positive duration, e.g. 500ms or 2s")
+		}
+	default:
+		return unknownKey(key)
+	}
+	return nil
+}
+
+// Validate checks all settings of the profile.
+func (configuration Configuration) Validate() []error {
+	var problems []error
+	for _, key := range ConfKeys {
+		value, _ := configuration.Get(key)
+		if err := validateSetting(key, value); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if (configuration.ClientCertFile == "") != (configuration.ClientKeyFile == "") {
+		problems = append(problems, errors.New("clientCertFile and clientKeyFile must be set together"))
+	}
+	return problems
+}
+
+// ValidateConfFile checks that the content is a valid conf.json: valid JSON without unknown settings, with valid settings
+// in every profile. The problems are returned keyed by profile name, or by "" for problems of the file.
+func ValidateConfFile(content []byte) map[string][]error {
+	problems := make(map[string][]error)
+	conf, migrated, err := ParseConfFile(content)
+	if err != nil {
+		problems[""] = append(problems[""], err)
+		return problems
+	}
+	if !migrated {
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&ConfFile{}); err != nil {
+			problems[""] = append(problems[""], err)
+		}
+	}
+	if len(conf.Profiles) == 0 {
+		problems[""] = append(problems[""], errors.New("no profiles defined"))
+	}
+	for name, configuration := range conf.Profiles {
+		if errorList := configuration.Validate(); len(errorList) > 0 {
+			problems[name] = errorList
+		}
+	}
+	return problems
+}
+
+// ApplyEnvironment overrides the settings with the SF_* environment variables which are set, and returns the names of
+// the settings taken from the environment.
+func ApplyEnvironment(configuration Configuration) (Configuration, map[string]string, error) {
+	overridden := make(map[string]string)
+	for _, variable := range []string{"SF_BROKER_NAME", "SF_EXT_URL", "SF_BROKER_URL", "SF_SKIP_SSL", "SF_MAX_RETRIES", "SF_RETRY_DELAY"} {
+		value, flag := os.LookupEnv(variable)
+		if !flag {
+			continue
+		}
+		if err := configuration.Set(confEnvironment[variable], value); err != nil {
+			return configuration, overridden, errors.New(variable + ": " + err.Error())
+		}
+		overridden[confEnvironment[variable]] = variable
+	}
+	return configuration, overridden, nil
+}
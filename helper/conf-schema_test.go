@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("configuration schema", func() {
+	It("Invalid values should be rejected", func() {
+		configuration := DefaultConfiguration()
+		Expect(configuration.Set("serviceBrokerUrl", "service-fabrik-broker.example.com")).To(HaveOccurred())
+		Expect(configuration.Set("skipSslFlag", "yes please")).To(HaveOccurred())
+		Expect(configuration.Set("brokerUrl", "https://example.com")).To(HaveOccurred())
+		Expect(configuration.Set("serviceBrokerUrl", "https://service-fabrik-broker.example.com")).To(Succeed())
+		Expect(configuration.Unset("serviceBrokerUrl")).To(Succeed())
+		Expect(configuration).To(Equal(DefaultConfiguration()))
+	})
+	It("Unknown settings and invalid profiles should be reported", func() {
+		problems := ValidateConfFile([]byte(`{"profiles": {"default": {"serviceBroker": "sf-broker", "serviceBrokerExtUrl": "/api/v1", "skipSsl": true}, "staging": {"serviceBroker": "", "clientCertFile": "/nonexistent.pem"}}}`))
+		Expect(problems[""]).To(HaveLen(1))
+		Expect(problems["default"]).To(BeEmpty())
+		Expect(problems["staging"]).To(HaveLen(3))
+		Expect(ValidateConfFile([]byte(`{"profiles": {`))[""]).To(HaveLen(1))
+	})
+	It("Environment variables should take precedence over the profile", func() {
+		os.Setenv("SF_BROKER_URL", "https://sf.example.com")
+		defer os.Unsetenv("SF_BROKER_URL")
+
+		configuration, overridden, err := ApplyEnvironment(DefaultConfiguration())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(configuration.ServiceBrokerUrl).To(Equal("https://sf.example.com"))
+		Expect(overridden).To(Equal(map[string]string{"serviceBrokerUrl": "SF_BROKER_URL"}))
+	})
+})
@@ -18,6 +18,8 @@ type Config struct {
 	OrganizationFields    OrgField
 	Target                string
 	AuthorizationEndpoint string
+	SSLDisabled           bool
+	Trace                 string
 }
 
 type SpaceField struct {
@@ -36,6 +38,7 @@ type TokenInfo struct {
 	UserGUID string   `json:"user_id"`
 	GUID     string   `json:"GUID"`
 	Scope    []string //`json:"scope":["cloud_controller.read","password.write","cloud_controller.write","openid","uaa.user"]`
+	Expiry   int64    `json:"exp"`
 }
 
 //this code taken from cf cli source code source
@@ -54,6 +57,44 @@ func NewTokenInfo(accessToken string) (info TokenInfo) {
 	return info
 }
 
+// RequiredScopes are the token scopes the plugin needs to read and change backups and service instances.
+var RequiredScopes = []string{"cloud_controller.read", "cloud_controller.write"}
+
+// HasScope reports whether the token grants the scope. cloud_controller.admin grants all cloud controller scopes and
+// cloud_controller.admin_read_only grants cloud_controller.read.
+func (info TokenInfo) HasScope(scope string) bool {
+	for _, granted := range info.Scope {
+		if granted == scope || (granted == "cloud_controller.admin" && strings.HasPrefix(scope, "cloud_controller.")) {
+			return true
+		}
+		if granted == "cloud_controller.admin_read_only" && scope == "cloud_controller.read" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the token grants cloud_controller.admin, which needs no org or space roles.
+func (info TokenInfo) IsAdmin() bool {
+	for _, granted := range info.Scope {
+		if granted == "cloud_controller.admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingScopes returns the given scopes which the token does not grant.
+func (info TokenInfo) MissingScopes(scopes ...string) []string {
+	var missing []string
+	for _, scope := range scopes {
+		if !info.HasScope(scope) {
+			missing = append(missing, scope)
+		}
+	}
+	return missing
+}
+
 func DecodeAccessToken(accessToken string) (tokenJSON []byte, err error) {
 	tokenParts := strings.Split(accessToken, " ")
 
@@ -114,6 +155,16 @@ func ReadConfigJsonFile() []byte {
 	return file
 }
 
+// GetCfConfigDir returns the .cf directory below CF_HOME, or below the home directory if CF_HOME is not set.
+func GetCfConfigDir() string {
+	var CF_HOME string = os.Getenv("CF_HOME")
+
+	if CF_HOME == "" {
+		CF_HOME = GetHomeDir()
+	}
+	return CF_HOME + string(os.PathSeparator) + ".cf"
+}
+
 func GetHomeDir() string {
 	homeDir, err := homedir.Dir()
 	if err != nil {
@@ -151,6 +202,10 @@ func GetRefreshToken(file []byte) string {
 }
 
 func GetSpaceGUID(file []byte) string {
+	if targetSpace != nil {
+		return targetSpace.SpaceGuid
+	}
+
 	var config Config
 
 	if err := json.Unmarshal(file, &config); err != nil {
@@ -166,6 +221,10 @@ func GetSpaceGUID(file []byte) string {
 }
 
 func GetSpaceName(file []byte) string {
+	if targetSpace != nil {
+		return targetSpace.SpaceName
+	}
+
 	var config Config
 
 	if err := json.Unmarshal(file, &config); err != nil {
@@ -181,6 +240,10 @@ func GetSpaceName(file []byte) string {
 }
 
 func GetOrgName(file []byte) string {
+	if targetSpace != nil {
+		return targetSpace.OrgName
+	}
+
 	var config Config
 
 	if err := json.Unmarshal(file, &config); err != nil {
@@ -195,6 +258,25 @@ func GetOrgName(file []byte) string {
 	return userOrgName
 }
 
+func GetOrgGUID(file []byte) string {
+	if targetSpace != nil {
+		return targetSpace.OrgGuid
+	}
+
+	var config Config
+
+	if err := json.Unmarshal(file, &config); err != nil {
+		fmt.Printf("Error parsing json [%v]\n", err)
+		os.Exit(4)
+	}
+
+	if config.OrganizationFields.GUID == "" {
+		errors.NoAccessTokenError("Organisation Fields")
+	}
+	var userOrgGuid string = strings.Trim(config.OrganizationFields.GUID, "\"")
+	return userOrgGuid
+}
+
 func GetApiEndpoint(file []byte) string {
 	var config Config
 
@@ -225,6 +307,17 @@ func GetLoginEndpoint(file []byte) string {
 
 }
 
+// GetSSLDisabled reports whether the cf CLI was targeted with --skip-ssl-validation.
+func GetSSLDisabled(file []byte) bool {
+	var config Config
+
+	if err := json.Unmarshal(file, &config); err != nil {
+		fmt.Printf("Error parsing json [%v]\n", err)
+		os.Exit(4)
+	}
+	return config.SSLDisabled
+}
+
 func Exists(path string) bool {
 
 	if _, err := os.Stat(path); err != nil {
@@ -235,39 +328,11 @@ func Exists(path string) bool {
 	return true
 }
 
+// CreateConfFile writes conf.json with the default profile if it does not exist yet.
 func CreateConfFile() {
-	var path string
-	var CF_HOME string = os.Getenv("CF_HOME")
-	if CF_HOME == "" {
-		CF_HOME = GetHomeDir()
-	}
-	path = CF_HOME + "/.cf/conf.json"
-
-	brace1 := []byte("{\n")
-	key1 := []byte("\"serviceBroker\": ")
-	val1 := []byte("\"service-fabrik-broker\",\n")
-	key2 := []byte("\"serviceBrokerExtUrl\": ")
-	val2 := []byte("\"/api/v1\",\n")
-	key3 := []byte("\"skipSslFlag\": ")
-	val3 := []byte("true\n")
-	brace2 := []byte("}")
-	if Exists(path) {
+	if Exists(GetConfFilePath()) {
 		return
-	} else {
-		f, err := os.Create(path)
-		errors.ErrorIsNil(err)
-
-		defer f.Close()
-
-		f.Write(brace1)
-		f.Write(key1)
-		f.Write(val1)
-		f.Write(key2)
-		f.Write(val2)
-		f.Write(key3)
-		f.Write(val3)
-		f.Write(brace2)
-
-		f.Sync()
 	}
+	err := WriteConfFile(ConfFile{Profiles: map[string]Configuration{DefaultProfile: DefaultConfiguration()}})
+	errors.ErrorIsNil(err)
 }
@@ -0,0 +1,205 @@
+package helper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+)
+
+// DefaultProfile is the profile used for API endpoints without a profile of their own.
+const DefaultProfile string = "default"
+
+// Configuration is the plugin configuration of one profile in conf.json. ServiceBrokerUrl is optional,
+// see GetBrokerApiUrl. CaCertFile, ClientCertFile and ClientKeyFile are optional paths to PEM files.
+// MaxRetries and RetryDelay override the retry policy of broker calls, see GetRetryPolicy.
+type Configuration struct {
+	ServiceBroker       string `json:"serviceBroker"`
+	ServiceBrokerExtUrl string `json:"serviceBrokerExtUrl"`
+	ServiceBrokerUrl    string `json:"serviceBrokerUrl,omitempty"`
+	SkipSslFlag         bool   `json:"skipSslFlag"`
+	CaCertFile          string `json:"caCertFile,omitempty"`
+	ClientCertFile      string `json:"clientCertFile,omitempty"`
+	ClientKeyFile       string `json:"clientKeyFile,omitempty"`
+	MaxRetries          *int   `json:"maxRetries,omitempty"`
+	RetryDelay          string `json:"retryDelay,omitempty"`
+}
+
+// ConfFile is the content of conf.json: the profiles keyed by API endpoint, or by DefaultProfile or any other name.
+type ConfFile struct {
+	Profiles map[string]Configuration `json:"profiles"`
+}
+
+var selectedProfile string
+
+// SetProfile makes GetConfiguration use the given profile instead of the one of the targeted API endpoint.
+func SetProfile(profile string) {
+	selectedProfile = profile
+}
+
+// GetProfile returns the profile given with --profile, or "" if the profile is chosen by the API endpoint.
+func GetProfile() string {
+	return selectedProfile
+}
+
+// DefaultConfiguration returns the settings written to a new conf.json.
+func DefaultConfiguration() Configuration {
+	return Configuration{ServiceBroker: "service-fabrik-broker", ServiceBrokerExtUrl: "/api/v1"}
+}
+
+// GetConfFilePath returns the path of conf.json.
+func GetConfFilePath() string {
+	return GetCfConfigDir() + string(os.PathSeparator) + "conf.json"
+}
+
+// profileKey normalizes an API endpoint for the lookup of its profile.
+func profileKey(apiEndpoint string) string {
+	return strings.TrimRight(strings.ToLower(apiEndpoint), "/")
+}
+
+// ParseConfFile decodes conf.json. A flat file of a previous version, which holds the settings of one foundation,
+// becomes the default profile; migrated reports whether this was the case. Previous versions always wrote skipSslFlag
+// as true, so it is dropped by the migration: verification is then skipped only if the cf CLI was targeted with
+// --skip-ssl-validation, unless skipSslFlag is set again.
+func ParseConfFile(content []byte) (conf ConfFile, migrated bool, err error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return ConfFile{}, false, err
+	}
+	if _, flag := fields["profiles"]; flag {
+		err = json.Unmarshal(content, &conf)
+		return conf, false, err
+	}
+
+	var configuration Configuration
+	if err := json.Unmarshal(content, &configuration); err != nil {
+		return ConfFile{}, false, err
+	}
+	configuration.SkipSslFlag = false
+	return ConfFile{Profiles: map[string]Configuration{DefaultProfile: configuration}}, true, nil
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func (conf ConfFile) ProfileNames() []string {
+	var names []string
+	for name := range conf.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select returns the name and the settings of the profile to use: the given profile if not empty, else the profile of
+// the API endpoint, else the default profile.
+func (conf ConfFile) Select(profile string, apiEndpoint string) (string, Configuration, error) {
+	if profile != "" {
+		if configuration, flag := conf.Profiles[profile]; flag {
+			return profile, configuration, nil
+		}
+		for name, configuration := range conf.Profiles {
+			if profileKey(name) == profileKey(profile) {
+				return name, configuration, nil
+			}
+		}
+		return "", Configuration{}, fmt.Errorf("profile %s not found in %s, available profiles: %s", profile, GetConfFilePath(), strings.Join(conf.ProfileNames(), ", "))
+	}
+
+	if apiEndpoint != "" {
+		for name, configuration := range conf.Profiles {
+			if profileKey(name) == profileKey(apiEndpoint) {
+				return name, configuration, nil
+			}
+		}
+	}
+	if configuration, flag := conf.Profiles[DefaultProfile]; flag {
+		return DefaultProfile, configuration, nil
+	}
+	return "", Configuration{}, fmt.Errorf("no profile for %s and no %s profile found in %s", apiEndpoint, DefaultProfile, GetConfFilePath())
+}
+
+// ReadConfFile reads conf.json and migrates a flat file of a previous version to profiles.
+func ReadConfFile() (ConfFile, error) {
+	content, err := ioutil.ReadFile(GetConfFilePath())
+	if err != nil {
+		return ConfFile{}, err
+	}
+	conf, migrated, err := ParseConfFile(content)
+	if err != nil {
+		return ConfFile{}, err
+	}
+	if migrated {
+		if err := WriteConfFile(conf); err != nil {
+			return ConfFile{}, err
+		}
+	}
+	return conf, nil
+}
+
+// WriteConfFile replaces conf.json atomically.
+func WriteConfFile(conf ConfFile) error {
+	content, err := json.MarshalIndent(conf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(GetConfFilePath(), append(content, '\n'), 0600)
+}
+
+// readCfConfig reads config.json without failing the command if it is missing or invalid.
+func readCfConfig() (Config, error) {
+	var config Config
+	content, err := ioutil.ReadFile(GetCfConfigDir() + string(os.PathSeparator) + "config.json")
+	if err != nil {
+		return config, err
+	}
+	err = json.Unmarshal(content, &config)
+	return config, err
+}
+
+// IsLoggedIn reports whether config.json holds an access token, without exiting like GetAccessToken if it does not.
+func IsLoggedIn() bool {
+	config, err := readCfConfig()
+	return err == nil && config.AccessToken != ""
+}
+
+// CurrentTarget returns the API endpoint targeted in config.json, or "" if there is none.
+func CurrentTarget() string {
+	config, err := readCfConfig()
+	if err != nil {
+		return ""
+	}
+	return config.Target
+}
+
+// GetSelectedProfile returns the name and the settings of the profile in use.
+func GetSelectedProfile() (string, Configuration, error) {
+	conf, err := ReadConfFile()
+	if err != nil {
+		return "", Configuration{}, err
+	}
+	return conf.Select(selectedProfile, CurrentTarget())
+}
+
+// LoadConfiguration returns the settings of the profile in use, overridden by the SF_* environment variables, or the error
+// why conf.json cannot be read or is invalid.
+func LoadConfiguration() (Configuration, error) {
+	_, configuration, err := GetSelectedProfile()
+	if err != nil {
+		return configuration, err
+	}
+	configuration, _, err = ApplyEnvironment(configuration)
+	return configuration, err
+}
+
+// GetConfiguration returns the settings of the profile in use like LoadConfiguration.
+// The command fails if conf.json cannot be read or is invalid.
+func GetConfiguration() Configuration {
+	configuration, err := LoadConfiguration()
+	if err != nil {
+		errors.InvalidConfiguration(GetConfFilePath(), err)
+	}
+	return configuration
+}
@@ -0,0 +1,41 @@
+package helper
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("configuration profiles", func() {
+	It("A flat conf.json should be migrated to the default profile", func() {
+		conf, migrated, err := ParseConfFile([]byte(`{"serviceBroker": "sf-broker", "serviceBrokerExtUrl": "/api/v1", "skipSslFlag": true}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migrated).To(BeTrue())
+		Expect(conf.Profiles[DefaultProfile].ServiceBroker).To(Equal("sf-broker"))
+	})
+	It("Migrating a flat conf.json with the old default should turn verification on", func() {
+		conf, migrated, err := ParseConfFile([]byte(`{"serviceBroker": "service-fabrik-broker", "serviceBrokerExtUrl": "/api/v1", "skipSslFlag": true}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migrated).To(BeTrue())
+		Expect(conf.Profiles[DefaultProfile].SkipSslFlag).To(BeFalse())
+
+		conf, _, _ = ParseConfFile([]byte(`{"profiles": {"default": {"serviceBroker": "sf-broker", "skipSslFlag": true}}}`))
+		Expect(conf.Profiles[DefaultProfile].SkipSslFlag).To(BeTrue())
+	})
+	It("The profile of the API endpoint should be preferred over the default profile", func() {
+		conf, migrated, err := ParseConfFile([]byte(`{"profiles": {"default": {"serviceBroker": "sf-broker"}, "https://api.cf.eu10.example.com": {"serviceBroker": "sf-broker-eu10"}}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migrated).To(BeFalse())
+
+		name, configuration, err := conf.Select("", "https://API.cf.eu10.example.com/")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(Equal("https://api.cf.eu10.example.com"))
+		Expect(configuration.ServiceBroker).To(Equal("sf-broker-eu10"))
+
+		name, _, _ = conf.Select("", "https://api.cf.us10.example.com")
+		Expect(name).To(Equal(DefaultProfile))
+		name, _, _ = conf.Select(DefaultProfile, "https://api.cf.eu10.example.com")
+		Expect(name).To(Equal(DefaultProfile))
+		_, _, err = conf.Select("staging", "")
+		Expect(err).To(HaveOccurred())
+	})
+})
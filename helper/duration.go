@@ -0,0 +1,19 @@
+package helper
+
+import (
+	"time"
+)
+
+// FormatDuration returns the time between two RFC3339 time stamps rounded to seconds, e.g. "4m12s",
+// or "null" if one of them is missing, e.g. because the operation has not finished yet.
+func FormatDuration(startedAt string, finishedAt string) string {
+	startTime, err := time.Parse(time.RFC3339, startedAt)
+	if err != nil {
+		return "null"
+	}
+	finishTime, err := time.Parse(time.RFC3339, finishedAt)
+	if err != nil {
+		return "null"
+	}
+	return finishTime.Sub(startTime).Round(time.Second).String()
+}
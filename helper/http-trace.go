@@ -0,0 +1,112 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry/cli/cf/trace"
+)
+
+// PrivateDataPlaceholder replaces credentials in the trace output.
+const PrivateDataPlaceholder string = "[PRIVATE DATA HIDDEN]"
+
+var tracePrinter trace.Printer
+
+var (
+	secretHeaderPattern = regexp.MustCompile(`(?im)^((?:Proxy-)?Authorization|Cookie|Set-Cookie):.*$`)
+	secretJSONPattern   = regexp.MustCompile(`(?i)"(access_token|refresh_token|id_token|token|password|client_secret)"(\s*):(\s*)"[^"]*"`)
+	secretFormPattern   = regexp.MustCompile(`(?i)\b(access_token|refresh_token|id_token|password|client_secret)=[^&\s]*`)
+	bearerTokenPattern  = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-_.~+/]+=*`)
+)
+
+// NewTracePrinter returns the printer for HTTP traces as configured like in the cf CLI: cfTrace is CF_TRACE and
+// configTrace the trace setting of config.json, each either a boolean or the path of a file the trace is appended to.
+// It returns nil if tracing is disabled. If a trace file cannot be opened, the trace is written to console instead.
+func NewTracePrinter(console io.Writer, cfTrace string, configTrace string) trace.Printer {
+	var printers []trace.Printer
+	var toConsole bool
+
+	for _, setting := range []string{cfTrace, configTrace} {
+		if setting == "" {
+			continue
+		}
+		if enabled, err := strconv.ParseBool(setting); err == nil {
+			toConsole = toConsole || enabled
+			continue
+		}
+		file, err := os.OpenFile(setting, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			fmt.Fprintln(console, "CF_TRACE error creating the log file", setting+":", err)
+			toConsole = true
+			continue
+		}
+		printers = append(printers, trace.NewWriterPrinter(file, false))
+	}
+
+	if toConsole {
+		printers = append(printers, trace.NewWriterPrinter(console, true))
+	}
+	if len(printers) == 0 {
+		return nil
+	}
+	return trace.CombinePrinters(printers)
+}
+
+// InitializeTrace enables the tracing of the plugin's HTTP clients according to CF_TRACE and the trace setting of
+// config.json, and returns the printer, which is nil if tracing is disabled.
+func InitializeTrace() trace.Printer {
+	config, _ := readCfConfig()
+	tracePrinter = NewTracePrinter(os.Stdout, os.Getenv("CF_TRACE"), config.Trace)
+	return tracePrinter
+}
+
+// RedactTrace hides the Authorization and cookie headers, bearer tokens, and tokens and passwords in JSON and form bodies.
+func RedactTrace(dump string) string {
+	dump = secretHeaderPattern.ReplaceAllString(dump, "$1: "+PrivateDataPlaceholder)
+	dump = secretJSONPattern.ReplaceAllString(dump, `"$1"$2:$3"`+PrivateDataPlaceholder+`"`)
+	dump = secretFormPattern.ReplaceAllString(dump, "$1="+PrivateDataPlaceholder)
+	return bearerTokenPattern.ReplaceAllString(dump, "bearer "+PrivateDataPlaceholder)
+}
+
+// TracingTransport prints every request and response, redacted and with timings, before passing them on.
+type TracingTransport struct {
+	Transport http.RoundTripper
+	Printer   trace.Printer
+}
+
+// NewTracingTransport wraps transport with tracing if tracing is enabled, and returns transport unchanged otherwise.
+func NewTracingTransport(transport http.RoundTripper) http.RoundTripper {
+	if tracePrinter == nil {
+		return transport
+	}
+	return &TracingTransport{Transport: transport, Printer: tracePrinter}
+}
+
+func (t *TracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	dump, err := httputil.DumpRequest(req, true)
+	if err != nil {
+		dump = []byte(req.Method + " " + req.URL.String())
+	}
+	t.Printer.Printf("\nREQUEST: [%s]\n%s\n", start.Format(time.RFC3339), RedactTrace(string(dump)))
+
+	resp, err := t.Transport.RoundTrip(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Printer.Printf("\nRESPONSE: [%s] took %s\nERROR: %s\n", time.Now().Format(time.RFC3339), elapsed, RedactTrace(err.Error()))
+		return resp, err
+	}
+
+	dump, err = httputil.DumpResponse(resp, true)
+	if err != nil {
+		dump = []byte(resp.Status)
+	}
+	t.Printer.Printf("\nRESPONSE: [%s] took %s\n%s\n", time.Now().Format(time.RFC3339), elapsed, RedactTrace(string(dump)))
+	return resp, nil
+}
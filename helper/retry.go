@@ -0,0 +1,205 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+)
+
+// clockSkew is the difference between the clocks of the broker and the client tolerated by StartedSince.
+const clockSkew = time.Minute
+
+// RetryPolicy is the number of retries of a failed broker call and the base delay of the exponential backoff.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// GetRetryPolicy returns the retry policy of the profile in use: maxRetries and retryDelay of conf.json, or
+// SF_MAX_RETRIES and SF_RETRY_DELAY, else the defaults of the constants package.
+func GetRetryPolicy() RetryPolicy {
+	configuration := GetConfiguration()
+	policy := RetryPolicy{
+		MaxRetries: constants.MaxRetries,
+		BaseDelay:  time.Duration(constants.RetryDelay) * time.Second,
+		MaxDelay:   time.Duration(constants.MaxRetryDelay) * time.Second,
+	}
+	if configuration.MaxRetries != nil {
+		policy.MaxRetries = *configuration.MaxRetries
+	}
+	if delay, err := time.ParseDuration(configuration.RetryDelay); err == nil && delay > 0 {
+		policy.BaseDelay = delay
+	}
+	return policy
+}
+
+// Delay returns the wait before the given retry, counted from 0: the base delay doubled with every retry, capped at
+// MaxDelay, of which a random part of up to one half is dropped so that concurrent clients do not retry in lockstep.
+func (policy RetryPolicy) Delay(retry int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 0; i < retry && delay < policy.MaxDelay; i++ {
+		delay = delay * 2
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if delay < 2 {
+		return delay
+	}
+	return delay - time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+// IsRetryableStatus reports whether a broker response is worth retrying: gateway errors of the router and the broker's
+// responses to another operation in progress on the instance.
+func IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout, http.StatusConflict, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// IsRetryableError reports whether a failed request may succeed when sent again, like after a reset or timed out connection.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if netErr, flag := err.(net.Error); flag && netErr.Timeout() {
+		return true
+	}
+	message := err.Error()
+	for _, transient := range []string{"connection reset", "broken pipe", "connection refused", "EOF", "timeout"} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSafeMethod reports whether a request may be sent again without a check: reads, and deletes, which abort operations.
+func isSafeMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "DELETE":
+		return true
+	}
+	return false
+}
+
+// retryAfter returns the wait requested by the Retry-After header in seconds, or 0.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// waitForRetry prints why the request is retried and waits for the delay of the retry.
+func waitForRetry(policy RetryPolicy, retry int, method string, url string, resp *http.Response, err error) {
+	delay := policy.Delay(retry)
+	if wait := retryAfter(resp); wait > delay && wait <= policy.MaxDelay {
+		delay = wait
+	}
+	var reason string
+	if err != nil {
+		reason = err.Error()
+	} else {
+		reason = resp.Status
+	}
+	fmt.Fprintf(os.Stderr, "%s %s failed with %s, retrying in %s (%d/%d) ...\n", method, strings.Split(url, "?")[0], reason, delay.Round(time.Millisecond), retry+1, policy.MaxRetries)
+	time.Sleep(delay)
+}
+
+// RetryTransport sends requests with safe methods again if they failed with a transient error.
+type RetryTransport struct {
+	Transport http.RoundTripper
+	Policy    RetryPolicy
+}
+
+// NewRetryTransport wraps transport with the retry policy of the profile in use.
+func NewRetryTransport(transport http.RoundTripper) http.RoundTripper {
+	return &RetryTransport{Transport: transport, Policy: GetRetryPolicy()}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !isSafeMethod(req.Method) {
+		return t.Transport.RoundTrip(req)
+	}
+	for retry := 0; ; retry++ {
+		resp, err := t.Transport.RoundTrip(req)
+		if retry >= t.Policy.MaxRetries || !(IsRetryableError(err) || (err == nil && IsRetryableStatus(resp.StatusCode))) {
+			return resp, err
+		}
+		if (req.Body != nil && req.GetBody == nil) || req.Context().Err() != nil {
+			return resp, err //The body cannot be sent again, or the client gave up.
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		waitForRetry(t.Policy, retry, req.Method, req.URL.String(), resp, err)
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return nil, bodyErr
+			}
+			req.Body = body
+		}
+	}
+}
+
+// StartedOperation is an operation which a previous attempt of StartBrokerOperation started after all. The broker does
+// not report the guid of every operation, so Guid may be empty.
+type StartedOperation struct {
+	Guid      string
+	StartedAt string
+}
+
+// StartBrokerOperation posts a request which starts an operation on the broker, like a backup or a restore. Such a
+// request is retried only if findStarted, which is given the time of the first attempt, finds no operation started by
+// one of the previous attempts, so that a retry never starts a second operation. If it finds one, it is returned
+// together with the last response.
+func StartBrokerOperation(client *http.Client, url string, body []byte, findStarted func(since time.Time) (*StartedOperation, error)) (*http.Response, []byte, *StartedOperation, error) {
+	policy := GetRetryPolicy()
+	firstAttempt := time.Now()
+	for retry := 0; ; retry++ {
+		resp, respBody, err := CallBroker(client, "POST", url, bytes.NewReader(body))
+		if retry >= policy.MaxRetries || !(IsRetryableError(err) || (err == nil && IsRetryableStatus(resp.StatusCode))) {
+			return resp, respBody, nil, err
+		}
+		waitForRetry(policy, retry, "POST", url, resp, err)
+
+		started, checkErr := findStarted(firstAttempt)
+		if checkErr != nil {
+			fmt.Fprintln(os.Stderr, "Cannot check whether the operation has already started, not retrying:", checkErr)
+			return resp, respBody, nil, err
+		}
+		if started != nil {
+			return resp, respBody, started, nil
+		}
+	}
+}
+
+// StartedSince reports whether an operation with the given RFC 3339 start time started after since, allowing for a
+// difference between the clocks of the broker and the client.
+func StartedSince(startedAt string, since time.Time) bool {
+	startTime, err := time.Parse(time.RFC3339, startedAt)
+	if err != nil {
+		return false
+	}
+	return !startTime.Before(since.Add(-clockSkew))
+}
@@ -0,0 +1,61 @@
+package helper
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("retry", func() {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 4 * time.Millisecond}
+
+	It("The delay should grow exponentially with jitter up to the maximum", func() {
+		slowPolicy := RetryPolicy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+		for i := 0; i < 20; i++ {
+			Expect(slowPolicy.Delay(0)).To(BeNumerically(">", 500*time.Millisecond))
+			Expect(slowPolicy.Delay(0)).To(BeNumerically("<=", time.Second))
+			Expect(slowPolicy.Delay(2)).To(BeNumerically(">", 2*time.Second))
+			Expect(slowPolicy.Delay(2)).To(BeNumerically("<=", 4*time.Second))
+			Expect(slowPolicy.Delay(10)).To(BeNumerically("<=", 30*time.Second))
+		}
+	})
+	It("Gateway errors and concurrent operations should be retried", func() {
+		Expect(IsRetryableStatus(http.StatusServiceUnavailable)).To(BeTrue())
+		Expect(IsRetryableStatus(http.StatusConflict)).To(BeTrue())
+		Expect(IsRetryableStatus(http.StatusForbidden)).To(BeFalse())
+	})
+	It("Reads should be retried until they succeed, but posts not", func() {
+		var calls int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		client := &http.Client{Transport: &RetryTransport{Transport: http.DefaultTransport, Policy: policy}}
+
+		resp, err := client.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(calls).To(Equal(3))
+
+		calls = 0
+		resp, err = client.Post(server.URL, "application/json", strings.NewReader(`{"type": "online"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(calls).To(Equal(1))
+	})
+	It("Operations started shortly before the first attempt should count as started by it", func() {
+		since, _ := time.Parse(time.RFC3339, "2018-11-12T11:45:26Z")
+		Expect(StartedSince("2018-11-12T11:45:00Z", since)).To(BeTrue())
+		Expect(StartedSince("2018-11-12T11:40:00Z", since)).To(BeFalse())
+		Expect(StartedSince("", since)).To(BeFalse())
+	})
+})
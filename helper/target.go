@@ -0,0 +1,33 @@
+package helper
+
+// TargetSpace is an org and space given on the command line. When set, it takes precedence over the
+// org and space targeted in config.json, so that a command can act on a space without running cf target.
+type TargetSpace struct {
+	OrgGuid   string
+	OrgName   string
+	SpaceGuid string
+	SpaceName string
+}
+
+var targetSpace *TargetSpace
+
+// SetTargetSpace makes GetSpaceGUID, GetSpaceName, GetOrgGUID and GetOrgName return the given org and space.
+func SetTargetSpace(target TargetSpace) {
+	targetSpace = &target
+}
+
+// GetTargetSpace returns the org and space given on the command line, or nil if the config.json target is used.
+func GetTargetSpace() *TargetSpace {
+	return targetSpace
+}
+
+// TargetedSpace returns the org and space commands act on: the one given on the command line, else the one targeted
+// in config.json.
+func TargetedSpace(file []byte) TargetSpace {
+	return TargetSpace{
+		OrgGuid:   GetOrgGUID(file),
+		OrgName:   GetOrgName(file),
+		SpaceGuid: GetSpaceGUID(file),
+		SpaceName: GetSpaceName(file),
+	}
+}
@@ -0,0 +1,85 @@
+package helper
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/fatih/color"
+)
+
+var insecureWarning sync.Once
+
+// SkipSslVerification reports whether TLS certificates are not verified. If SF_SKIP_SSL is set, it decides; otherwise
+// verification is skipped if skipSslFlag is set in conf.json or if the cf CLI was targeted with --skip-ssl-validation.
+func SkipSslVerification() bool {
+	if _, flag := os.LookupEnv("SF_SKIP_SSL"); flag {
+		return GetConfiguration().SkipSslFlag
+	}
+	return GetConfiguration().SkipSslFlag || GetSSLDisabled(ReadConfigJsonFile())
+}
+
+// caCertFile returns the CA bundle to trust in addition to the system certificates: caCertFile of conf.json, or else SSL_CERT_FILE.
+func caCertFile(configuration Configuration) string {
+	if configuration.CaCertFile != "" {
+		return configuration.CaCertFile
+	}
+	return os.Getenv("SSL_CERT_FILE")
+}
+
+// NewTLSConfig returns the TLS configuration of the plugin's HTTP clients. It trusts the system certificates and the
+// configured CA bundle and presents the configured client certificate. A warning is printed once if verification is skipped.
+func NewTLSConfig(skipVerification bool) *tls.Config {
+	configuration := GetConfiguration()
+	tlsConfig := &tls.Config{InsecureSkipVerify: skipVerification}
+
+	if skipVerification {
+		insecureWarning.Do(func() {
+			fmt.Fprintln(os.Stderr, color.New(color.FgYellow).Add(color.Bold).SprintFunc()("WARNING:"), "TLS certificate verification is disabled. Set \"skipSslFlag\": false in "+GetConfFilePath()+" and target the cf CLI without --skip-ssl-validation to enable it.")
+		})
+	}
+
+	if path := caCertFile(configuration); path != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			errors.FileReadingError(path)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			errors.FileReadingError(path)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if configuration.ClientCertFile != "" || configuration.ClientKeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(configuration.ClientCertFile, configuration.ClientKeyFile)
+		if err != nil {
+			errors.FileReadingError(configuration.ClientCertFile + ", " + configuration.ClientKeyFile)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig
+}
+
+// NewHttpClient returns an HTTP client which verifies TLS certificates unless SkipSslVerification says otherwise.
+// Its requests are traced if CF_TRACE is set, and reads and deletes are retried according to GetRetryPolicy.
+func NewHttpClient() *http.Client {
+	return &http.Client{
+		Transport: NewRetryTransport(NewTracingTransport(&http.Transport{
+			MaxIdleConnsPerHost: constants.MaxIdleConnections,
+			TLSClientConfig:     NewTLSConfig(SkipSslVerification()),
+			Proxy:               http.ProxyFromEnvironment,
+		})),
+		Timeout: time.Duration(constants.RequestTimeout) * time.Second,
+	}
+}
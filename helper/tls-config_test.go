@@ -0,0 +1,25 @@
+package helper
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLS configuration", func() {
+	var savedCertFile string
+
+	BeforeEach(func() {
+		savedCertFile = os.Getenv("SSL_CERT_FILE")
+		os.Setenv("SSL_CERT_FILE", "/etc/ssl/bundle.pem")
+	})
+	AfterEach(func() {
+		os.Setenv("SSL_CERT_FILE", savedCertFile)
+	})
+
+	It("The CA bundle of conf.json should take precedence over SSL_CERT_FILE", func() {
+		Expect(caCertFile(Configuration{CaCertFile: "/home/user/ca.pem"})).To(Equal("/home/user/ca.pem"))
+		Expect(caCertFile(Configuration{})).To(Equal("/etc/ssl/bundle.pem"))
+	})
+})
@@ -0,0 +1,131 @@
+package permission
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+	"github.com/fatih/color"
+)
+
+// RequiredSpaceRole is the role a user needs in the space for commands which change backups or service instances.
+const RequiredSpaceRole string = "space_developer"
+
+type PermissionCommand struct {
+	cliConnection plugin.CliConnection
+}
+
+func NewPermissionCommand(cliConnection plugin.CliConnection) *PermissionCommand {
+	command := new(PermissionCommand)
+	command.cliConnection = cliConnection
+	return command
+}
+
+func AddColor(text string, textColor color.Attribute) string {
+	printer := color.New(textColor).Add(color.Bold).SprintFunc()
+	return printer(text)
+}
+
+// HasSpaceRole reports whether the user may change backups and service instances of the space: with the SpaceDeveloper
+// role, or with an admin token, which needs no roles.
+func HasSpaceRole(info helper.TokenInfo, spaceRoles []string) bool {
+	if info.IsAdmin() {
+		return true
+	}
+	for _, role := range spaceRoles {
+		if role == RequiredSpaceRole {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleNames returns the cf CLI names of the role types, or "none".
+func RoleNames(roleTypes []string) string {
+	if len(roleTypes) == 0 {
+		return "none"
+	}
+	var names []string
+	for _, roleType := range roleTypes {
+		names = append(names, guidTranslator.RoleName(roleType))
+	}
+	return strings.Join(names, ", ")
+}
+
+// FormatExpiry describes when the token expires, relative to now.
+func FormatExpiry(info helper.TokenInfo, now time.Time) string {
+	if info.Expiry == 0 {
+		return "unknown"
+	}
+	expiry := time.Unix(info.Expiry, 0)
+	if !expiry.After(now) {
+		return expiry.UTC().Format(time.RFC3339) + " (expired, refreshed by the next cf command)"
+	}
+	return expiry.UTC().Format(time.RFC3339) + " (in " + expiry.Sub(now).Round(time.Minute).String() + ")"
+}
+
+// findRoles looks up the roles of the user in the org and space. The lookup goes through cf curl, which also refreshes
+// an expired token, so the token is read afterwards.
+func findRoles(cliConnection plugin.CliConnection, orgGuid string, spaceGuid string) (helper.TokenInfo, []string, []string, error) {
+	userGuid := helper.NewTokenInfo(helper.GetAccessToken(helper.ReadConfigJsonFile())).UserGUID
+	orgRoles, spaceRoles, err := guidTranslator.FindUserRoles(cliConnection, userGuid, orgGuid, spaceGuid)
+	info := helper.NewTokenInfo(helper.GetAccessToken(helper.ReadConfigJsonFile()))
+	return info, orgRoles, spaceRoles, err
+}
+
+// Preflight checks before a command which changes backups or service instances of the space that the token grants the
+// required scopes and that the user has the SpaceDeveloper role in the space. It fails naming what is missing. If the
+// roles cannot be looked up, a warning is printed and the broker is left to decide.
+func Preflight(cliConnection plugin.CliConnection, space helper.TargetSpace) {
+	info, _, spaceRoles, err := findRoles(cliConnection, space.OrgGuid, space.SpaceGuid)
+	if missing := info.MissingScopes(helper.RequiredScopes...); len(missing) > 0 {
+		errors.MissingScopes(info.Username, strings.Join(missing, ", "))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot check your roles in the space:", err)
+		return
+	}
+	if !HasSpaceRole(info, spaceRoles) {
+		errors.MissingRole(guidTranslator.RoleName(RequiredSpaceRole), space.OrgName, space.SpaceName)
+	}
+}
+
+// Whoami shows the user, the roles in the targeted org and space, the scopes and the expiry of the token.
+func (c *PermissionCommand) Whoami(cliConnection plugin.CliConnection) {
+	space := helper.TargetedSpace(helper.ReadConfigJsonFile())
+	fmt.Println("Getting the user information for the org", AddColor(space.OrgName, constants.Cyan), "/ space", AddColor(space.SpaceName, constants.Cyan), "...")
+
+	info, orgRoles, spaceRoles, err := findRoles(cliConnection, space.OrgGuid, space.SpaceGuid)
+	if err != nil {
+		fmt.Println(AddColor("FAILED", constants.Red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Println(AddColor("OK", constants.Green))
+
+	var permitted string = "yes"
+	if missing := info.MissingScopes(helper.RequiredScopes...); len(missing) > 0 {
+		permitted = "no, the token lacks " + strings.Join(missing, ", ")
+	} else if !HasSpaceRole(info, spaceRoles) {
+		permitted = "no, the " + guidTranslator.RoleName(RequiredSpaceRole) + " role is missing"
+	}
+
+	table := backup.NewTable()
+	table.SetHeader([]string{" ", " "})
+	table.Append([]string{"user", info.Username})
+	table.Append([]string{"user guid", info.UserGUID})
+	table.Append([]string{"org roles", RoleNames(orgRoles)})
+	table.Append([]string{"space roles", RoleNames(spaceRoles)})
+	table.Append([]string{"admin", fmt.Sprint(info.IsAdmin())})
+	table.Append([]string{"scopes", strings.Join(info.Scope, ", ")})
+	table.Append([]string{"token expires", FormatExpiry(info, time.Now())})
+	table.Append([]string{"backup and restore", permitted})
+	table.Render()
+}
@@ -0,0 +1,41 @@
+package permission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPermission(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Permission Suite")
+}
+
+var _ = Describe("permission", func() {
+	developer := helper.TokenInfo{Username: "dev", Scope: []string{"cloud_controller.read", "cloud_controller.write"}}
+	auditor := helper.TokenInfo{Username: "auditor", Scope: []string{"cloud_controller.admin_read_only"}}
+	admin := helper.TokenInfo{Username: "admin", Scope: []string{"cloud_controller.admin"}}
+
+	It("The SpaceDeveloper role should be required unless the token is an admin token", func() {
+		Expect(HasSpaceRole(developer, []string{"space_auditor", "space_developer"})).To(BeTrue())
+		Expect(HasSpaceRole(developer, []string{"space_manager"})).To(BeFalse())
+		Expect(HasSpaceRole(admin, nil)).To(BeTrue())
+	})
+	It("Missing scopes should be reported", func() {
+		Expect(developer.MissingScopes(helper.RequiredScopes...)).To(BeEmpty())
+		Expect(admin.MissingScopes(helper.RequiredScopes...)).To(BeEmpty())
+		Expect(auditor.MissingScopes(helper.RequiredScopes...)).To(Equal([]string{"cloud_controller.write"}))
+	})
+	It("Roles should be shown with the names of the cf CLI", func() {
+		Expect(RoleNames([]string{"organization_user", "space_developer"})).To(Equal("OrgUser, SpaceDeveloper"))
+		Expect(RoleNames(nil)).To(Equal("none"))
+	})
+	It("The expiry of the token should be shown relative to now", func() {
+		now := time.Unix(1500547000, 0)
+		Expect(FormatExpiry(helper.TokenInfo{Expiry: 1500547900}, now)).To(Equal("2017-07-20T10:51:40Z (in 15m0s)"))
+		Expect(FormatExpiry(helper.TokenInfo{Expiry: 1500546000}, now)).To(ContainSubstring("expired"))
+	})
+})
@@ -0,0 +1,113 @@
+package restore
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+func failClone(err error) {
+	fmt.Println(AddColor("FAILED", red))
+	fmt.Println(err)
+	os.Exit(1)
+}
+
+// failCloneWithInstance fails the clone after the new instance was created. The instance is left for inspection, so it
+// is named together with how to delete it, as a rerun fails as long as its name is taken.
+func failCloneWithInstance(err error, newName string, newGuid string, targetSpace helper.TargetSpace) {
+	fmt.Println(AddColor("FAILED", red))
+	fmt.Println(err)
+	fmt.Println("The new service instance", AddColor(newName, cyan), "("+newGuid+") in the org", targetSpace.OrgName, "/ space", targetSpace.SpaceName, "was not restored.")
+	fmt.Println("Delete it with 'cf delete-service " + newName + "' in that space before cloning again.")
+	os.Exit(1)
+}
+
+// CloneService creates a new instance of the source instance's service and restores a backup of the source instance into it.
+// The backup is the given backup guid or the newest succeeded backup. With timeStamp, the new instance is restored to that
+// point in time like by start-restore --timestamp, which the broker does from the newest backup started before it.
+// The new instance is created in the target space.
+func (c *RestoreCommand) CloneService(cliConnection plugin.CliConnection, sourceName string, newName string, backupGuid string, timeStamp string, targetSpace helper.TargetSpace, planName string) {
+	fmt.Println("Cloning", AddColor(sourceName, cyan), "to", AddColor(newName, cyan), "...")
+
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	var before time.Time
+	if timeStamp != "" {
+		parsedTimestamp, err := time.Parse(time.RFC3339, timeStamp)
+		if err != nil {
+			fmt.Println(AddColor("FAILED", red))
+			fmt.Println(err)
+			fmt.Println("Please enter time in ISO8061 format, example - 2018-11-12T11:45:26.371Z, 2018-11-12T11:45:26Z")
+			os.Exit(1)
+		}
+		before = parsedTimestamp
+	}
+
+	client := GetHttpClient()
+	var sourceSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	var sourceGuid string = guidTranslator.FindInstanceGuid(cliConnection, sourceName, nil, sourceSpaceGuid)
+
+	sourcePlan, err := FindInstancePlan(cliConnection, sourceGuid)
+	if err != nil {
+		failClone(err)
+	}
+	planGuid, err := FindCompatiblePlan(cliConnection, sourcePlan, planName)
+	if err != nil {
+		failClone(err)
+	}
+
+	var targetSpaceGuid string = targetSpace.SpaceGuid
+
+	records, err := backup.GetBackupRecords(client, sourceSpaceGuid, sourceGuid)
+	if err != nil {
+		failClone(err)
+	}
+	selected := selectBackup(records, sourceGuid, backupGuid, before)
+	if selected == nil {
+		failClone(fmt.Errorf("no matching succeeded backup found for %s", sourceName))
+	}
+	request := RestoreRequest{BackupGuid: selected.BackupGuid, SpaceGuid: sourceSpaceGuid}
+	if timeStamp != "" {
+		request = RestoreRequest{TimeStamp: before, SpaceGuid: sourceSpaceGuid, SourceInstanceGuid: sourceGuid}
+		fmt.Println("Restoring to", AddColor(timeStamp, cyan), "from the backup", AddColor(selected.BackupGuid, cyan), "(started at "+selected.StartedAt+")")
+	} else {
+		fmt.Println("Using backup", AddColor(selected.BackupGuid, cyan), "(started at "+selected.StartedAt+")")
+	}
+
+	fmt.Println("Creating service instance", AddColor(newName, cyan), "...")
+	targetGuid, err := CreateServiceInstance(cliConnection, newName, targetSpaceGuid, planGuid)
+	if err != nil {
+		failClone(err)
+	}
+	if err := WaitForServiceInstance(cliConnection, targetGuid); err != nil {
+		failCloneWithInstance(err, newName, targetGuid, targetSpace)
+	}
+
+	fmt.Println("Restoring", AddColor(newName, cyan), "from the backup ...")
+	if _, err := TriggerRestore(client, targetGuid, targetSpaceGuid, request); err != nil {
+		failCloneWithInstance(err, newName, targetGuid, targetSpace)
+	}
+	restoreState, err := WaitForRestore(client, targetGuid, targetSpaceGuid)
+	if err != nil {
+		failCloneWithInstance(err, newName, targetGuid, targetSpace)
+	}
+	if restoreState != constants.BackupStateSucceeded {
+		failCloneWithInstance(fmt.Errorf("restore finished with state %s", restoreState), newName, targetGuid, targetSpace)
+	}
+
+	fmt.Println(AddColor("OK", green))
+	if timeStamp != "" {
+		fmt.Println("Instance", AddColor(newName, cyan), "has been created from", AddColor(sourceName, cyan), "as of", AddColor(timeStamp, cyan))
+		return
+	}
+	fmt.Println("Instance", AddColor(newName, cyan), "has been created from the backup", AddColor(selected.BackupGuid, cyan), "of", AddColor(sourceName, cyan))
+}
@@ -0,0 +1,55 @@
+package restore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRestore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Restore Suite")
+}
+
+var _ = Describe("selecting the backup to restore", func() {
+	var records []backup.BackupRecord
+
+	BeforeEach(func() {
+		records = []backup.BackupRecord{
+			{BackupGuid: "b1", InstanceGuid: "i1", State: "succeeded", StartedAt: "2018-11-10T00:00:00Z"},
+			{BackupGuid: "b2", InstanceGuid: "i1", State: "succeeded", StartedAt: "2018-11-12T00:00:00Z"},
+			{BackupGuid: "b3", InstanceGuid: "i1", State: "failed", StartedAt: "2018-11-13T00:00:00Z"},
+			{BackupGuid: "b4", InstanceGuid: "i2", State: "succeeded", StartedAt: "2018-11-14T00:00:00Z"},
+		}
+	})
+
+	It("The newest succeeded backup of the instance should be selected by default", func() {
+		Expect(selectBackup(records, "i1", "", time.Time{}).BackupGuid).To(Equal("b2"))
+	})
+	It("Only backups started before the time stamp should be considered", func() {
+		before, _ := time.Parse(time.RFC3339, "2018-11-11T00:00:00Z")
+		Expect(selectBackup(records, "i1", "", before).BackupGuid).To(Equal("b1"))
+	})
+	It("Backups of other instances or failed backups should not be selected", func() {
+		Expect(selectBackup(records, "i1", "b4", time.Time{})).To(BeNil())
+		Expect(selectBackup(records, "i1", "b3", time.Time{})).To(BeNil())
+	})
+})
+
+var _ = Describe("restore requests", func() {
+	It("A restore from a backup should name the backup", func() {
+		Expect(string(RestoreRequest{BackupGuid: "b1"}.Body())).To(Equal(`{"backup_guid":"b1"}`))
+		Expect(string(RestoreRequest{BackupGuid: "b1", SpaceGuid: "s1"}.Body())).To(Equal(`{"backup_guid":"b1","space_guid":"s1"}`))
+	})
+	It("A point in time restore should send the time stamp in epoch milliseconds", func() {
+		timeStamp, _ := time.Parse(time.RFC3339, "2018-11-12T11:45:26.371Z")
+		Expect(string(RestoreRequest{TimeStamp: timeStamp, SpaceGuid: "s1"}.Body())).To(Equal(`{"space_guid":"s1","time_stamp":"1542023126371"}`))
+	})
+	It("A point in time clone should name the source instance", func() {
+		timeStamp, _ := time.Parse(time.RFC3339, "2018-11-12T11:45:26Z")
+		Expect(string(RestoreRequest{TimeStamp: timeStamp, SpaceGuid: "s1", SourceInstanceGuid: "i1"}.Body())).To(Equal(`{"instance_guid":"i1","space_guid":"s1","time_stamp":"1542023126000"}`))
+	})
+})
@@ -0,0 +1,323 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/errors"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// RecoveryState records the progress of a recover-instance run so that an interrupted run can be resumed.
+type RecoveryState struct {
+	DeletedName     string `json:"deleted_name"`
+	OldInstanceGuid string `json:"old_instance_guid"`
+	NewName         string `json:"new_name"`
+	BackupGuid      string `json:"backup_guid"`
+	PlanGuid        string `json:"plan_guid"`
+	NewInstanceGuid string `json:"new_instance_guid"`
+	RestoreGuid     string `json:"restore_guid"`
+	SpaceGuid       string `json:"space_guid"`
+	CompletedSteps  int    `json:"completed_steps"`
+}
+
+var recoverySteps = []string{
+	"Resolving deleted instance",
+	"Selecting backup",
+	"Creating service instance",
+	"Waiting for service instance",
+	"Starting restore",
+	"Waiting for restore",
+}
+
+// recoveryStateFile returns the name of the state file of the recovery of the deleted instance name in the space. The
+// name is escaped, so that it cannot leave the cf config dir.
+func recoveryStateFile(spaceGuid string, deletedName string) string {
+	return "sf-recover-" + spaceGuid + "-" + url.QueryEscape(deletedName) + ".json"
+}
+
+func recoveryStatePath(spaceGuid string, deletedName string) string {
+	return helper.GetCfConfigDir() + string(os.PathSeparator) + recoveryStateFile(spaceGuid, deletedName)
+}
+
+func loadRecoveryState(spaceGuid string, deletedName string) (*RecoveryState, error) {
+	content, err := ioutil.ReadFile(recoveryStatePath(spaceGuid, deletedName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := new(RecoveryState)
+	if err := json.Unmarshal(content, state); err != nil {
+		return nil, err
+	}
+	if state.SpaceGuid != spaceGuid || state.DeletedName != deletedName {
+		return nil, fmt.Errorf("the file belongs to the recovery of %s in the space %s", state.DeletedName, state.SpaceGuid)
+	}
+	return state, nil
+}
+
+func (state *RecoveryState) save() {
+	content, _ := json.MarshalIndent(state, "", "  ")
+	if err := helper.WriteFileAtomic(recoveryStatePath(state.SpaceGuid, state.DeletedName), content, 0600); err != nil {
+		fmt.Println("Warning: could not save the recovery state:", err)
+	}
+}
+
+func failRecovery(state *RecoveryState, err error) {
+	fmt.Println(AddColor("FAILED", red))
+	fmt.Println(err)
+	if state.CompletedSteps > 0 {
+		fmt.Println("Run 'cf recover-instance " + state.DeletedName + "' again to resume the recovery.")
+	}
+	os.Exit(1)
+}
+
+// recoveryActions are the calls of the recovery steps to the cloud controller and the broker.
+type recoveryActions interface {
+	BackupRecords(instanceGuid string) ([]backup.BackupRecord, error)
+	ResolveDeletedInstance(deletedName string, records []backup.BackupRecord) string
+	FindPlanGuid(planId string) (string, error)
+	FindInstance(name string) (string, string, error)
+	CreateInstance(name string, planGuid string) (string, error)
+	WaitForInstance(instanceGuid string) error
+	FindRestore(instanceGuid string, backupGuid string) (*helper.StartedOperation, error)
+	TriggerRestore(instanceGuid string, backupGuid string) (string, error)
+	WaitForRestore(instanceGuid string) (string, error)
+}
+
+// cfRecoveryActions are the recovery actions in the space of the recovery.
+type cfRecoveryActions struct {
+	cliConnection plugin.CliConnection
+	client        *http.Client
+	spaceGuid     string
+}
+
+func (actions cfRecoveryActions) BackupRecords(instanceGuid string) ([]backup.BackupRecord, error) {
+	return backup.GetBackupRecords(actions.client, actions.spaceGuid, instanceGuid)
+}
+
+func (actions cfRecoveryActions) ResolveDeletedInstance(deletedName string, records []backup.BackupRecord) string {
+	return backup.ResolveDeletedInstance(actions.cliConnection, actions.spaceGuid, deletedName, records, "")
+}
+
+func (actions cfRecoveryActions) FindPlanGuid(planId string) (string, error) {
+	return FindPlanGuid(actions.cliConnection, planId)
+}
+
+func (actions cfRecoveryActions) FindInstance(name string) (string, string, error) {
+	return FindSpaceInstance(actions.cliConnection, actions.spaceGuid, name)
+}
+
+func (actions cfRecoveryActions) CreateInstance(name string, planGuid string) (string, error) {
+	return CreateServiceInstance(actions.cliConnection, name, actions.spaceGuid, planGuid)
+}
+
+func (actions cfRecoveryActions) WaitForInstance(instanceGuid string) error {
+	return WaitForServiceInstance(actions.cliConnection, instanceGuid)
+}
+
+func (actions cfRecoveryActions) FindRestore(instanceGuid string, backupGuid string) (*helper.StartedOperation, error) {
+	return findStartedRestore(actions.client, instanceGuid, actions.spaceGuid, backupGuid)(time.Time{})
+}
+
+func (actions cfRecoveryActions) TriggerRestore(instanceGuid string, backupGuid string) (string, error) {
+	triggered, err := TriggerRestore(actions.client, instanceGuid, actions.spaceGuid, RestoreRequest{BackupGuid: backupGuid, SpaceGuid: actions.spaceGuid})
+	return triggered.Guid, err
+}
+
+func (actions cfRecoveryActions) WaitForRestore(instanceGuid string) (string, error) {
+	return WaitForRestore(actions.client, instanceGuid, actions.spaceGuid)
+}
+
+// recovery runs the steps of a recovery. A resumed recovery may find the effects of a step which was interrupted after
+// the cloud controller or the broker accepted it, so steps which create something first check whether it exists.
+type recovery struct {
+	state   *RecoveryState
+	actions recoveryActions
+	resumed bool
+	records []backup.BackupRecord
+}
+
+// runStep runs the given step, counted from 0, and records its results in the state.
+func (r *recovery) runStep(step int) error {
+	state := r.state
+	var err error
+	switch step {
+	case 0:
+		if r.records == nil {
+			if r.records, err = r.actions.BackupRecords(""); err != nil {
+				return err
+			}
+		}
+		if state.OldInstanceGuid == "" {
+			state.OldInstanceGuid = r.actions.ResolveDeletedInstance(state.DeletedName, r.records)
+			if state.OldInstanceGuid == "" {
+				return fmt.Errorf("%s maps to multiple deleted instances, use --guid to choose one", state.DeletedName)
+			}
+		}
+		fmt.Println("  deleted instance guid:", AddColor(state.OldInstanceGuid, cyan))
+	case 1:
+		if r.records == nil {
+			if r.records, err = r.actions.BackupRecords(state.OldInstanceGuid); err != nil {
+				return err
+			}
+		}
+		selected := selectBackup(r.records, state.OldInstanceGuid, state.BackupGuid, time.Time{})
+		if selected == nil {
+			if state.BackupGuid != "" {
+				return fmt.Errorf("backup %s of instance %s not found or not succeeded", state.BackupGuid, state.OldInstanceGuid)
+			}
+			return fmt.Errorf("no succeeded backup found for instance %s", state.OldInstanceGuid)
+		}
+		state.BackupGuid = selected.BackupGuid
+		if state.PlanGuid, err = r.actions.FindPlanGuid(selected.PlanId); err != nil {
+			return err
+		}
+		fmt.Println("  backup:", AddColor(selected.BackupGuid, cyan), "(started at "+selected.StartedAt+")")
+	case 2:
+		existingGuid, existingPlanGuid, err := r.actions.FindInstance(state.NewName)
+		if err != nil {
+			return err
+		}
+		switch {
+		case existingGuid == "":
+			if state.NewInstanceGuid, err = r.actions.CreateInstance(state.NewName, state.PlanGuid); err != nil {
+				return err
+			}
+		case r.resumed && existingPlanGuid == state.PlanGuid:
+			state.NewInstanceGuid = existingGuid //Created by the interrupted run.
+			fmt.Println("  instance", AddColor(state.NewName, cyan), "was already created")
+		default:
+			return fmt.Errorf("a service instance named %s already exists in the space, use --new-name to choose another name", state.NewName)
+		}
+		fmt.Println("  new instance guid:", AddColor(state.NewInstanceGuid, cyan))
+	case 3:
+		if err := r.actions.WaitForInstance(state.NewInstanceGuid); err != nil {
+			return err
+		}
+	case 4:
+		started, err := r.actions.FindRestore(state.NewInstanceGuid, state.BackupGuid)
+		if err != nil {
+			return err
+		}
+		if started != nil {
+			state.RestoreGuid = started.Guid //Started by the interrupted run.
+			fmt.Println("  restore was already started at", started.StartedAt)
+		} else if state.RestoreGuid, err = r.actions.TriggerRestore(state.NewInstanceGuid, state.BackupGuid); err != nil {
+			return err
+		}
+		if state.RestoreGuid != "" {
+			fmt.Println("  restore guid:", AddColor(state.RestoreGuid, cyan))
+		}
+	case 5:
+		restoreState, err := r.actions.WaitForRestore(state.NewInstanceGuid)
+		if err != nil {
+			return err
+		}
+		if restoreState != constants.BackupStateSucceeded {
+			return fmt.Errorf("restore finished with state %s", restoreState)
+		}
+	}
+	state.CompletedSteps = step + 1
+	return nil
+}
+
+// conflictingFlags returns the flags given to resume a recovery whose values differ from the recorded ones.
+func conflictingFlags(state *RecoveryState, oldGuid string, newName string, backupGuid string) []string {
+	var conflicts []string
+	if oldGuid != "" && oldGuid != state.OldInstanceGuid {
+		conflicts = append(conflicts, "--guid "+oldGuid)
+	}
+	if newName != "" && newName != state.NewName {
+		conflicts = append(conflicts, "--new-name "+newName)
+	}
+	if backupGuid != "" && backupGuid != state.BackupGuid {
+		conflicts = append(conflicts, "--backup_guid "+backupGuid)
+	}
+	return conflicts
+}
+
+// RecoverInstance recreates a deleted service instance with the same service plan and restores it from a backup of the
+// deleted instance. The progress is kept in a state file below CF_HOME, so that an interrupted recovery resumes where it stopped.
+func (c *RestoreCommand) RecoverInstance(cliConnection plugin.CliConnection, deletedName string, oldGuid string, newName string, backupGuid string) {
+	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
+		errors.NoAccessTokenError("Access Token")
+	}
+
+	var spaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	state, err := loadRecoveryState(spaceGuid, deletedName)
+	if err != nil {
+		fmt.Println(AddColor("FAILED", red))
+		fmt.Println("Could not read the recovery state file", recoveryStatePath(spaceGuid, deletedName)+":", err)
+		os.Exit(1)
+	}
+	var resumed bool = state != nil
+	if resumed {
+		if conflicts := conflictingFlags(state, oldGuid, newName, backupGuid); len(conflicts) > 0 {
+			fmt.Println(AddColor("FAILED", red))
+			fmt.Println("A recovery of", deletedName, "as", state.NewName, "is in progress and cannot be changed with", strings.Join(conflicts, ", ")+".")
+			fmt.Println("Run 'cf recover-instance " + deletedName + "' without these flags to resume it, or delete " + recoveryStatePath(spaceGuid, deletedName) + " to start over.")
+			os.Exit(1)
+		}
+		fmt.Println("Resuming recovery of", AddColor(deletedName, cyan), "after step", strconv.Itoa(state.CompletedSteps)+"/"+strconv.Itoa(len(recoverySteps)), "...")
+	} else {
+		if newName == "" {
+			newName = deletedName
+		}
+		state = &RecoveryState{
+			DeletedName:     deletedName,
+			OldInstanceGuid: oldGuid,
+			NewName:         newName,
+			BackupGuid:      backupGuid,
+			SpaceGuid:       spaceGuid,
+		}
+		fmt.Println("Recovering deleted instance", AddColor(deletedName, cyan), "as", AddColor(newName, cyan), "...")
+	}
+
+	run := &recovery{
+		state:   state,
+		actions: cfRecoveryActions{cliConnection: cliConnection, client: GetHttpClient(), spaceGuid: state.SpaceGuid},
+		resumed: resumed,
+	}
+	for step := state.CompletedSteps; step < len(recoverySteps); step++ {
+		fmt.Println("Step " + strconv.Itoa(step+1) + "/" + strconv.Itoa(len(recoverySteps)) + ": " + recoverySteps[step] + " ...")
+		if err := run.runStep(step); err != nil {
+			failRecovery(state, err)
+		}
+		state.save()
+	}
+
+	os.Remove(recoveryStatePath(spaceGuid, deletedName))
+	fmt.Println(AddColor("OK", green))
+	fmt.Println("Instance", AddColor(state.NewName, cyan), "has been recovered from the backup", AddColor(state.BackupGuid, cyan), "of the deleted instance", AddColor(state.OldInstanceGuid, cyan))
+}
+
+// selectBackup returns the given backup of the instance, or its newest succeeded backup if backupGuid is empty.
+// If before is not the zero time, only backups started at or before it are considered.
+func selectBackup(records []backup.BackupRecord, instanceGuid string, backupGuid string, before time.Time) *backup.BackupRecord {
+	backup.SortBackupRecords(records)
+	for index, record := range records {
+		if record.InstanceGuid != instanceGuid || !record.IsSucceeded() {
+			continue
+		}
+		if !before.IsZero() && record.StartTime().After(before) {
+			continue
+		}
+		if backupGuid == "" || record.BackupGuid == backupGuid {
+			return &records[index]
+		}
+	}
+	return nil
+}
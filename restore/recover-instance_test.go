@@ -0,0 +1,143 @@
+package restore
+
+import (
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeRecoveryActions records the calls of the recovery steps and answers them from its fields.
+type fakeRecoveryActions struct {
+	calls            []string
+	existingGuid     string
+	existingPlanGuid string
+	startedRestore   *helper.StartedOperation
+}
+
+func (actions *fakeRecoveryActions) BackupRecords(instanceGuid string) ([]backup.BackupRecord, error) {
+	actions.calls = append(actions.calls, "records")
+	return []backup.BackupRecord{
+		{BackupGuid: "b1", InstanceGuid: "old", PlanId: "p1", State: "succeeded", StartedAt: "2018-11-10T00:00:00Z"},
+		{BackupGuid: "b2", InstanceGuid: "old", PlanId: "p1", State: "succeeded", StartedAt: "2018-11-12T00:00:00Z"},
+	}, nil
+}
+
+func (actions *fakeRecoveryActions) ResolveDeletedInstance(deletedName string, records []backup.BackupRecord) string {
+	actions.calls = append(actions.calls, "resolve")
+	return "old"
+}
+
+func (actions *fakeRecoveryActions) FindPlanGuid(planId string) (string, error) {
+	return "plan-" + planId, nil
+}
+
+func (actions *fakeRecoveryActions) FindInstance(name string) (string, string, error) {
+	actions.calls = append(actions.calls, "find instance")
+	return actions.existingGuid, actions.existingPlanGuid, nil
+}
+
+func (actions *fakeRecoveryActions) CreateInstance(name string, planGuid string) (string, error) {
+	actions.calls = append(actions.calls, "create")
+	return "new", nil
+}
+
+func (actions *fakeRecoveryActions) WaitForInstance(instanceGuid string) error {
+	actions.calls = append(actions.calls, "wait instance")
+	return nil
+}
+
+func (actions *fakeRecoveryActions) FindRestore(instanceGuid string, backupGuid string) (*helper.StartedOperation, error) {
+	actions.calls = append(actions.calls, "find restore")
+	return actions.startedRestore, nil
+}
+
+func (actions *fakeRecoveryActions) TriggerRestore(instanceGuid string, backupGuid string) (string, error) {
+	actions.calls = append(actions.calls, "restore "+backupGuid)
+	return "r1", nil
+}
+
+func (actions *fakeRecoveryActions) WaitForRestore(instanceGuid string) (string, error) {
+	actions.calls = append(actions.calls, "wait restore")
+	return "succeeded", nil
+}
+
+// stateAfter returns the state of a recovery which completed the given number of steps.
+func stateAfter(completedSteps int) *RecoveryState {
+	state := &RecoveryState{DeletedName: "db", NewName: "db", SpaceGuid: "s1", CompletedSteps: completedSteps}
+	if completedSteps > 0 {
+		state.OldInstanceGuid = "old"
+	}
+	if completedSteps > 1 {
+		state.BackupGuid, state.PlanGuid = "b2", "plan-p1"
+	}
+	if completedSteps > 2 {
+		state.NewInstanceGuid = "new"
+	}
+	if completedSteps > 4 {
+		state.RestoreGuid = "r1"
+	}
+	return state
+}
+
+func runRecovery(run *recovery) error {
+	for step := run.state.CompletedSteps; step < len(recoverySteps); step++ {
+		if err := run.runStep(step); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var _ = Describe("recovering a deleted instance", func() {
+	allCalls := []string{"records", "resolve", "find instance", "create", "wait instance", "find restore", "restore b2", "wait restore"}
+	callsFrom := map[int][]string{
+		0: allCalls,
+		1: {"records", "find instance", "create", "wait instance", "find restore", "restore b2", "wait restore"},
+		2: {"find instance", "create", "wait instance", "find restore", "restore b2", "wait restore"},
+		3: {"wait instance", "find restore", "restore b2", "wait restore"},
+		4: {"find restore", "restore b2", "wait restore"},
+		5: {"wait restore"},
+	}
+
+	It("A recovery should resume from every step and complete with the same result", func() {
+		for completedSteps := 0; completedSteps < len(recoverySteps); completedSteps++ {
+			actions := new(fakeRecoveryActions)
+			run := &recovery{state: stateAfter(completedSteps), actions: actions, resumed: completedSteps > 0}
+			Expect(runRecovery(run)).To(Succeed())
+			Expect(actions.calls).To(Equal(callsFrom[completedSteps]), "resumed after step %d", completedSteps)
+			Expect(*run.state).To(Equal(*stateAfter(len(recoverySteps))), "resumed after step %d", completedSteps)
+		}
+	})
+	It("A resumed recovery should take over the instance created by the interrupted run", func() {
+		actions := &fakeRecoveryActions{existingGuid: "new", existingPlanGuid: "plan-p1"}
+		run := &recovery{state: stateAfter(2), actions: actions, resumed: true}
+		Expect(runRecovery(run)).To(Succeed())
+		Expect(actions.calls).NotTo(ContainElement("create"))
+		Expect(run.state.NewInstanceGuid).To(Equal("new"))
+	})
+	It("An instance with the new name should not be taken over by a new recovery or with another plan", func() {
+		actions := &fakeRecoveryActions{existingGuid: "other", existingPlanGuid: "plan-p1"}
+		Expect((&recovery{state: stateAfter(2), actions: actions}).runStep(2)).To(MatchError(ContainSubstring("already exists")))
+		actions = &fakeRecoveryActions{existingGuid: "other", existingPlanGuid: "plan-p2"}
+		Expect((&recovery{state: stateAfter(2), actions: actions, resumed: true}).runStep(2)).To(MatchError(ContainSubstring("already exists")))
+	})
+	It("A resumed recovery should not start a second restore", func() {
+		actions := &fakeRecoveryActions{startedRestore: &helper.StartedOperation{Guid: "r1", StartedAt: "2018-11-12T11:00:00Z"}}
+		run := &recovery{state: stateAfter(4), actions: actions, resumed: true}
+		Expect(runRecovery(run)).To(Succeed())
+		Expect(actions.calls).To(Equal([]string{"find restore", "wait restore"}))
+		Expect(run.state.RestoreGuid).To(Equal("r1"))
+	})
+	It("Flags which differ from the recovery in progress should be reported", func() {
+		state := stateAfter(3)
+		Expect(conflictingFlags(state, "", "", "")).To(BeEmpty())
+		Expect(conflictingFlags(state, "old", "db", "b2")).To(BeEmpty())
+		Expect(conflictingFlags(state, "other", "db2", "b1")).To(Equal([]string{"--guid other", "--new-name db2", "--backup_guid b1"}))
+	})
+	It("The state file should be keyed by the space and stay in the cf config dir", func() {
+		Expect(recoveryStateFile("s1", "db")).To(Equal("sf-recover-s1-db.json"))
+		Expect(recoveryStateFile("s2", "db")).NotTo(Equal(recoveryStateFile("s1", "db")))
+		Expect(recoveryStateFile("s1", "../../db")).NotTo(ContainSubstring("/"))
+	})
+})
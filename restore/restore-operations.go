@@ -0,0 +1,126 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+)
+
+func getBrokerApiUrl() string {
+	return helper.GetBrokerApiUrl()
+}
+
+// RestoreRequest is what the broker restores an instance from: a backup guid, or else a point in time. SpaceGuid is the
+// space of the backups and SourceInstanceGuid the instance they belong to, if it is not the restored instance.
+type RestoreRequest struct {
+	BackupGuid         string
+	TimeStamp          time.Time
+	SpaceGuid          string
+	SourceInstanceGuid string
+}
+
+// Body returns the JSON body of the restore request to the broker. A point in time is sent in epoch milliseconds.
+func (request RestoreRequest) Body() []byte {
+	body := make(map[string]string)
+	if request.BackupGuid != "" {
+		body["backup_guid"] = request.BackupGuid
+	} else {
+		body["time_stamp"] = strconv.FormatInt(request.TimeStamp.UnixNano()/1000000, 10)
+	}
+	if request.SpaceGuid != "" {
+		body["space_guid"] = request.SpaceGuid
+	}
+	if request.SourceInstanceGuid != "" {
+		body["instance_guid"] = request.SourceInstanceGuid
+	}
+	requestBody, _ := json.Marshal(body)
+	return requestBody
+}
+
+// TriggeredRestore is a restore started by TriggerRestore. Guid is "" if the broker does not report it; a restore started
+// by a previous attempt is then identified by its start time. Operation is the name of the broker operation, if reported.
+type TriggeredRestore struct {
+	Guid      string
+	StartedAt string
+	Operation string
+	Previous  bool
+}
+
+// TriggerRestore starts a restore of the given instance in instanceSpaceGuid as described by the request. A failed
+// attempt is only retried if it did not start a restore after all.
+func TriggerRestore(client *http.Client, instanceGuid string, instanceSpaceGuid string, request RestoreRequest) (TriggeredRestore, error) {
+	resp, body, started, err := helper.StartBrokerOperation(client, getBrokerApiUrl()+"/service_instances/"+instanceGuid+"/restore", request.Body(), findStartedRestore(client, instanceGuid, instanceSpaceGuid, request.BackupGuid))
+	if started != nil {
+		return TriggeredRestore{Guid: started.Guid, StartedAt: started.StartedAt, Previous: true}, nil
+	}
+	if err != nil {
+		return TriggeredRestore{}, err
+	}
+	if resp.Status != constants.AcceptedHttpStatusResponse {
+		return TriggeredRestore{}, helper.BrokerError(resp, body)
+	}
+
+	var respObject map[string]interface{}
+	json.Unmarshal(body, &respObject)
+	var triggered TriggeredRestore
+	triggered.Guid, _ = respObject["guid"].(string)
+	triggered.Operation, _ = respObject["name"].(string)
+	return triggered, nil
+}
+
+// findStartedRestore returns a check for StartBrokerOperation which finds a restore of the instance from the backup
+// guid, if given, started by the user since the first attempt.
+func findStartedRestore(client *http.Client, instanceGuid string, userSpaceGuid string, backupGuid string) func(time.Time) (*helper.StartedOperation, error) {
+	return func(since time.Time) (*helper.StartedOperation, error) {
+		status, err := helper.GetRestoreStatus(client, instanceGuid, userSpaceGuid)
+		if err != nil || status == nil {
+			return nil, err
+		}
+		return startedRestore(status, since, helper.NewTokenInfo(helper.GetAccessToken(helper.ReadConfigJsonFile())).Username, backupGuid), nil
+	}
+}
+
+// startedRestore returns the restore described by the restore status of the broker if the user started it since the
+// given time from the backup guid, if given, else nil. The broker does not report the guid of every restore.
+func startedRestore(status map[string]interface{}, since time.Time, username string, backupGuid string) *helper.StartedOperation {
+	startedAt, _ := status["started_at"].(string)
+	restoredBy, _ := status["username"].(string)
+	restoredBackup, _ := status["backup_guid"].(string)
+	if !helper.StartedSince(startedAt, since) || restoredBy != username {
+		return nil
+	}
+	if backupGuid != "" && restoredBackup != backupGuid {
+		return nil
+	}
+	restoreGuid, _ := status["guid"].(string)
+	return &helper.StartedOperation{Guid: restoreGuid, StartedAt: startedAt}
+}
+
+// WaitForRestore polls the last restore operation of the given instance until it is no longer in progress and returns its
+// final state. A restore which the broker does not report yet counts as in progress.
+func WaitForRestore(client *http.Client, instanceGuid string, userSpaceGuid string) (string, error) {
+	deadline := time.Now().Add(time.Duration(constants.OperationTimeout) * time.Second)
+	for {
+		status, err := helper.GetRestoreStatus(client, instanceGuid, userSpaceGuid)
+		if err != nil {
+			return "", err
+		}
+		var state string = constants.BackupStateProcessing
+		if status != nil {
+			state, _ = status["state"].(string)
+		}
+		if state != constants.BackupStateProcessing && state != constants.BackupStateAborting {
+			return state, nil
+		}
+		if time.Now().After(deadline) {
+			return state, fmt.Errorf("restore did not finish within %d seconds", constants.OperationTimeout)
+		}
+		fmt.Println("  restore", state, "...")
+		time.Sleep(time.Duration(constants.PollInterval) * time.Second)
+	}
+}
@@ -0,0 +1,27 @@
+package restore
+
+import (
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restores started by a previous attempt", func() {
+	since, _ := time.Parse(time.RFC3339, "2018-11-12T11:00:00Z")
+
+	It("A restore of the user since the first attempt should be found with its guid", func() {
+		status := map[string]interface{}{"guid": "r1", "username": "dev", "backup_guid": "b1", "started_at": "2018-11-12T11:00:30Z"}
+		Expect(startedRestore(status, since, "dev", "b1")).To(Equal(&helper.StartedOperation{Guid: "r1", StartedAt: "2018-11-12T11:00:30Z"}))
+	})
+	It("A restore without guid should be found without one instead of with its start time", func() {
+		status := map[string]interface{}{"username": "dev", "started_at": "2018-11-12T11:00:30Z"}
+		Expect(startedRestore(status, since, "dev", "")).To(Equal(&helper.StartedOperation{StartedAt: "2018-11-12T11:00:30Z"}))
+	})
+	It("Older restores, restores of other users and of other backups should not be found", func() {
+		Expect(startedRestore(map[string]interface{}{"username": "dev", "started_at": "2018-11-12T10:00:00Z"}, since, "dev", "")).To(BeNil())
+		Expect(startedRestore(map[string]interface{}{"username": "ops", "started_at": "2018-11-12T11:00:30Z"}, since, "dev", "")).To(BeNil())
+		Expect(startedRestore(map[string]interface{}{"username": "dev", "backup_guid": "b2", "started_at": "2018-11-12T11:00:30Z"}, since, "dev", "b1")).To(BeNil())
+	})
+})
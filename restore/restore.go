@@ -1,10 +1,9 @@
 package restore
 
 import (
-	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"github.com/SAP/service-fabrik-cli-plugin/backup"
 	"github.com/SAP/service-fabrik-cli-plugin/constants"
 	"github.com/SAP/service-fabrik-cli-plugin/errors"
 	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
@@ -15,7 +14,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -42,52 +40,20 @@ func AddColor(text string, textColor color.Attribute) string {
 	return printer(text)
 }
 
-type Configuration struct {
-	ServiceBroker       string
-	ServiceBrokerExtUrl string
-	SkipSslFlag         bool
-}
-
 func GetBrokerName() string {
-	return getConfiguration().ServiceBroker
+	return helper.GetConfiguration().ServiceBroker
 }
 
 func GetExtUrl() string {
-	return getConfiguration().ServiceBrokerExtUrl
+	return helper.GetConfiguration().ServiceBrokerExtUrl
 }
 
 func GetskipSslFlag() bool {
-	return getConfiguration().SkipSslFlag
-}
-
-func getConfiguration() Configuration {
-	var path string
-	var CF_HOME string = os.Getenv("CF_HOME")
-	if CF_HOME == "" {
-		CF_HOME = helper.GetHomeDir()
-	}
-	path = CF_HOME + "/.cf/conf.json"
-
-	file, _ := os.Open(path)
-	decoder := json.NewDecoder(file)
-	configuration := Configuration{}
-	err := decoder.Decode(&configuration)
-	if err != nil {
-		fmt.Println("error:", err)
-	}
-	return configuration
+	return helper.SkipSslVerification()
 }
 
 func GetHttpClient() *http.Client {
-	//Skip ssl verification.
-	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: GetskipSslFlag()},
-			Proxy:           http.ProxyFromEnvironment,
-		},
-		Timeout: time.Duration(180) * time.Second,
-	}
-	return client
+	return helper.NewHttpClient()
 }
 
 func GetResponse(client *http.Client, req *http.Request) *http.Response {
@@ -100,19 +66,23 @@ func GetResponse(client *http.Client, req *http.Request) *http.Response {
 	return resp
 }
 
-func (c *RestoreCommand) StartRestore(cliConnection plugin.CliConnection, serviceInstanceName string, backupId string, timeStamp string, isGuidOperation bool) {
-	fmt.Println("Starting restore for ", AddColor(serviceInstanceName, cyan), "...")
+// StartRestore restores the instance from the backup id or, unless isGuidOperation, to the time stamp. With waitForIdle,
+// it first waits up to idleTimeout until no other operation is in progress on the instance.
+func (c *RestoreCommand) StartRestore(cliConnection plugin.CliConnection, serviceInstanceName string, instanceGuid string, inputGuidBool bool, backupId string, timeStamp string, isGuidOperation bool, waitForIdle bool, idleTimeout time.Duration) {
+	if inputGuidBool == true {
+		fmt.Println("Starting restore for ", AddColor(instanceGuid, cyan), "...")
+	} else {
+		fmt.Println("Starting restore for ", AddColor(serviceInstanceName, cyan), "...")
+	}
 
 	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
 		errors.NoAccessTokenError("Access Token")
 	}
-	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	guid, serviceInstanceName, userSpaceGuid := guidTranslator.ResolveInstance(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 	client := GetHttpClient()
-	var req_body = bytes.NewBuffer([]byte(""))
+	var request RestoreRequest
 	if isGuidOperation == true {
-		var jsonPrep string = `{"backup_guid": "` + backupId + `"}`
-		var jsonStr = []byte(jsonPrep)
-		req_body = bytes.NewBuffer(jsonStr)
+		request = RestoreRequest{BackupGuid: backupId}
 	} else {
 		parsedTimestamp, err := time.Parse(time.RFC3339, timeStamp)
 		if err != nil {
@@ -121,66 +91,50 @@ func (c *RestoreCommand) StartRestore(cliConnection plugin.CliConnection, servic
 			fmt.Println("Please enter time in ISO8061 format, example - 2018-11-12T11:45:26.371Z, 2018-11-12T11:45:26Z")
 			return
 		}
-		var epochTime string = strconv.FormatInt(parsedTimestamp.UnixNano()/1000000, 10)
-		var jsonprep string = `{"time_stamp": "` + epochTime + `", "space_guid": "` + userSpaceGuid + `"}`
-		var jsonStr = []byte(jsonprep)
-		req_body = bytes.NewBuffer(jsonStr)
+		request = RestoreRequest{TimeStamp: parsedTimestamp, SpaceGuid: userSpaceGuid}
 	}
-	fmt.Println(req_body)
-	var guid string = guidTranslator.FindInstanceGuid(cliConnection, serviceInstanceName, nil, "")
-
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
-
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
-
-	var url string = apiEndpoint + extUrl + "/service_instances/" + guid + "/restore"
-	req, err := http.NewRequest("POST", url, req_body)
-	var resp *http.Response = GetResponse(client, req)
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-
-	var respObject map[string]interface{}
-
-	if err := json.Unmarshal(body, &respObject); err != nil {
-		fmt.Println(err)
-	}
-
-	if respStatus, flag := respObject["status"].(float64); flag != false {
-		if respStatus != 202 {
+	if waitForIdle {
+		if err := backup.WaitForIdle(cliConnection, client, guid, userSpaceGuid, idleTimeout); err != nil {
 			fmt.Println(AddColor("FAILED", red))
-			if respError, flag := respObject["error"].(string); flag != false {
-				fmt.Println("Error: ", respError)
-			}
-			if respMessage, flag := respObject["description"].(string); flag != false {
-				fmt.Println("Message: ", respMessage)
-			}
+			fmt.Println(err)
+			os.Exit(1)
 		}
 	}
+	triggered, err := TriggerRestore(client, guid, userSpaceGuid, request)
+	if err != nil {
+		fmt.Println(AddColor("FAILED", red))
+		fmt.Println(err)
+		os.Exit(1)
+	}
 
-	if resp.Status == constants.AcceptedHttpStatusResponse {
-		fmt.Println(AddColor("OK", green))
-		if respOperation, flag := respObject["name"].(string); flag != false {
-			fmt.Println("Operation: ", respOperation)
-		}
-		if restoreGuid, flag := respObject["guid"].(string); flag != false {
-			fmt.Println("Restore Guid: ", restoreGuid)
-		}
+	fmt.Println(AddColor("OK", green))
+	if triggered.Previous {
+		fmt.Println("The restore was started by a previous attempt.")
+	}
+	if triggered.Operation != "" {
+		fmt.Println("Operation: ", triggered.Operation)
+	}
+	if triggered.Guid != "" {
+		fmt.Println("Restore Guid: ", triggered.Guid)
+	} else if triggered.StartedAt != "" {
+		fmt.Println("Started at: ", triggered.StartedAt)
+	}
+	if !triggered.Previous {
 		if isGuidOperation == true {
 			fmt.Println("Restore has been initiated for the instance name:", AddColor(serviceInstanceName, cyan), " and from the backup id:", AddColor(backupId, cyan))
 		} else {
 			fmt.Println("Restore has been initiated for the instance name:", AddColor(serviceInstanceName, cyan), " using time stamp:", AddColor(timeStamp, cyan))
 		}
-		fmt.Println("Please check the status of restore by entering 'cf restore SERVICE_INSTANCE_NAME'")
 	}
-
-	errors.ErrorIsNil(err)
-
+	fmt.Println("Please check the status of restore by entering 'cf restore SERVICE_INSTANCE_NAME'")
 }
 
-func (c *RestoreCommand) RestoreInfo(cliConnection plugin.CliConnection, serviceInstanceName string) {
-	fmt.Println("Showing the status of the last restore operation for", AddColor(serviceInstanceName, cyan), " ...")
+func (c *RestoreCommand) RestoreInfo(cliConnection plugin.CliConnection, serviceInstanceName string, instanceGuid string, inputGuidBool bool) {
+	if inputGuidBool == true {
+		fmt.Println("Showing the status of the last restore operation for", AddColor(instanceGuid, cyan), " ...")
+	} else {
+		fmt.Println("Showing the status of the last restore operation for", AddColor(serviceInstanceName, cyan), " ...")
+	}
 
 	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
 		errors.NoAccessTokenError("Access Token")
@@ -188,17 +142,11 @@ func (c *RestoreCommand) RestoreInfo(cliConnection plugin.CliConnection, service
 
 	client := GetHttpClient()
 
-	var guid string = guidTranslator.FindInstanceGuid(cliConnection, serviceInstanceName, nil, "")
-
-	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
+	guid, serviceInstanceName, userSpaceGuid := guidTranslator.ResolveInstance(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
-
-	var url string = apiEndpoint + extUrl + "/service_instances/" + guid + "/restore?space_guid=" + userSpaceGuid
+	var url string = apiEndpoint + "/service_instances/" + guid + "/restore?space_guid=" + userSpaceGuid
 
 	req, err := http.NewRequest("GET", url, nil)
 
@@ -275,7 +223,7 @@ func (c *RestoreCommand) RestoreInfo(cliConnection plugin.CliConnection, service
 		}
 
 		if field, flag := respObject["started_at"].(string); flag != false {
-			table.Append([]string{"trigger", field})
+			table.Append([]string{"started_at", field})
 		}
 
 		if _, flag := respObject["finished_at"].(string); flag {
@@ -283,13 +231,20 @@ func (c *RestoreCommand) RestoreInfo(cliConnection plugin.CliConnection, service
 		} else {
 			table.Append([]string{"finished_at", "null"})
 		}
+		startedAt, _ := respObject["started_at"].(string)
+		finishedAt, _ := respObject["finished_at"].(string)
+		table.Append([]string{"duration", helper.FormatDuration(startedAt, finishedAt)})
 		table.Render()
 	}
 	errors.ErrorIsNil(err)
 }
 
-func (c *RestoreCommand) AbortRestore(cliConnection plugin.CliConnection, serviceInstanceName string) {
-	fmt.Println("Aborting restore for ", AddColor(serviceInstanceName, cyan), "...")
+func (c *RestoreCommand) AbortRestore(cliConnection plugin.CliConnection, serviceInstanceName string, instanceGuid string, inputGuidBool bool) {
+	if inputGuidBool == true {
+		fmt.Println("Aborting restore for ", AddColor(instanceGuid, cyan), "...")
+	} else {
+		fmt.Println("Aborting restore for ", AddColor(serviceInstanceName, cyan), "...")
+	}
 
 	if helper.GetAccessToken(helper.ReadConfigJsonFile()) == "" {
 		errors.NoAccessTokenError("Access Token")
@@ -297,17 +252,11 @@ func (c *RestoreCommand) AbortRestore(cliConnection plugin.CliConnection, servic
 
 	client := GetHttpClient()
 
-	var guid string = guidTranslator.FindInstanceGuid(cliConnection, serviceInstanceName, nil, "")
-
-	var userSpaceGuid string = helper.GetSpaceGUID(helper.ReadConfigJsonFile())
-
-	var apiEndpoint string = helper.GetApiEndpoint(helper.ReadConfigJsonFile())
-	var broker string = GetBrokerName()
-	var extUrl string = GetExtUrl()
+	guid, serviceInstanceName, userSpaceGuid := guidTranslator.ResolveInstance(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 
-	apiEndpoint = strings.Replace(apiEndpoint, "api", broker, 1)
+	var apiEndpoint string = helper.GetBrokerApiUrl()
 
-	var url string = apiEndpoint + extUrl + "/service_instances/" + guid + "/restore?space_guid=" + userSpaceGuid
+	var url string = apiEndpoint + "/service_instances/" + guid + "/restore?space_guid=" + userSpaceGuid
 	req, err := http.NewRequest("DELETE", url, nil)
 
 	var resp *http.Response = GetResponse(client, req)
@@ -0,0 +1,105 @@
+package restore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
+	"github.com/cloudfoundry/cli/plugin"
+)
+
+// FindPlanGuid returns the cloud controller guid of the service plan with the given broker plan id.
+func FindPlanGuid(cliConnection plugin.CliConnection, planId string) (string, error) {
+	resources, err := guidTranslator.CurlResources(cliConnection, "/v2/service_plans?q=unique_id:"+planId)
+	if err != nil {
+		return "", err
+	}
+	if len(resources) == 0 {
+		return "", fmt.Errorf("service plan %s not found", planId)
+	}
+	return guidTranslator.StringField(guidTranslator.Metadata(resources[0]), "guid"), nil
+}
+
+// CreateServiceInstance asynchronously creates a service instance of the given plan in the space and returns its guid.
+func CreateServiceInstance(cliConnection plugin.CliConnection, serviceInstanceName string, userSpaceGuid string, planGuid string) (string, error) {
+	requestBody, _ := json.Marshal(map[string]string{"name": serviceInstanceName, "space_guid": userSpaceGuid, "service_plan_guid": planGuid})
+
+	response, err := guidTranslator.CurlObject(cliConnection, "/v2/service_instances?accepts_incomplete=true", "-X", "POST", "-d", string(requestBody))
+	if err != nil {
+		return "", err
+	}
+	return guidTranslator.StringField(guidTranslator.Metadata(response), "guid"), nil
+}
+
+// FindSpaceInstance returns the guid and the plan guid of the service instance with the given name in the space, or
+// empty strings if there is none.
+func FindSpaceInstance(cliConnection plugin.CliConnection, userSpaceGuid string, serviceInstanceName string) (string, string, error) {
+	resources, err := guidTranslator.CurlResources(cliConnection, "/v2/spaces/"+userSpaceGuid+"/service_instances?q=name:"+url.QueryEscape(serviceInstanceName))
+	if err != nil || len(resources) == 0 {
+		return "", "", err
+	}
+	return guidTranslator.StringField(guidTranslator.Metadata(resources[0]), "guid"), guidTranslator.StringField(guidTranslator.Entity(resources[0]), "service_plan_guid"), nil
+}
+
+// WaitForServiceInstance polls the last operation of the given service instance until it is no longer in progress.
+func WaitForServiceInstance(cliConnection plugin.CliConnection, instanceGuid string) error {
+	deadline := time.Now().Add(time.Duration(constants.OperationTimeout) * time.Second)
+	for {
+		response, err := guidTranslator.CurlObject(cliConnection, "/v2/service_instances/"+instanceGuid)
+		if err != nil {
+			return err
+		}
+		lastOperation, _ := guidTranslator.Entity(response)["last_operation"].(map[string]interface{})
+		state := guidTranslator.StringField(lastOperation, "state")
+		switch state {
+		case constants.OperationStateSucceeded:
+			return nil
+		case constants.OperationStateFailed:
+			return fmt.Errorf("service instance creation failed: %s", guidTranslator.StringField(lastOperation, "description"))
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service instance was not created within %d seconds", constants.OperationTimeout)
+		}
+		fmt.Println("  create", state, "...")
+		time.Sleep(time.Duration(constants.PollInterval) * time.Second)
+	}
+}
+
+// FindInstancePlan returns the cloud controller service plan resource of the given service instance.
+func FindInstancePlan(cliConnection plugin.CliConnection, instanceGuid string) (map[string]interface{}, error) {
+	instance, err := guidTranslator.CurlObject(cliConnection, "/v2/service_instances/"+instanceGuid)
+	if err != nil {
+		return nil, err
+	}
+	return guidTranslator.CurlObject(cliConnection, "/v2/service_plans/"+guidTranslator.StringField(guidTranslator.Entity(instance), "service_plan_guid"))
+}
+
+// FindCompatiblePlan returns the guid of the plan with the given name of the same service as sourcePlan. A plan is
+// compatible if it belongs to the same service and is active; whether the service supports restoring a backup of the
+// source plan into it, e.g. into a smaller plan, is left to the broker. An empty plan name selects sourcePlan itself.
+func FindCompatiblePlan(cliConnection plugin.CliConnection, sourcePlan map[string]interface{}, planName string) (string, error) {
+	sourceEntity := guidTranslator.Entity(sourcePlan)
+	if planName == "" {
+		planName = guidTranslator.StringField(sourceEntity, "name")
+	}
+
+	var serviceGuid string = guidTranslator.StringField(sourceEntity, "service_guid")
+	plans, err := guidTranslator.CurlResources(cliConnection, "/v2/services/"+serviceGuid+"/service_plans")
+	if err != nil {
+		return "", err
+	}
+	for _, plan := range plans {
+		entity := guidTranslator.Entity(plan)
+		if guidTranslator.StringField(entity, "name") != planName {
+			continue
+		}
+		if active, _ := entity["active"].(bool); !active {
+			return "", fmt.Errorf("plan %s is not active", planName)
+		}
+		return guidTranslator.StringField(guidTranslator.Metadata(plan), "guid"), nil
+	}
+	return "", fmt.Errorf("plan %s is not a plan of the service of the source instance", planName)
+}
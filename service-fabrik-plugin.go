@@ -6,12 +6,18 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"code.cloudfoundry.org/cli/plugin"
 	"github.com/SAP/service-fabrik-cli-plugin/backup"
+	"github.com/SAP/service-fabrik-cli-plugin/config"
+	"github.com/SAP/service-fabrik-cli-plugin/constants"
+	"github.com/SAP/service-fabrik-cli-plugin/doctor"
 	"github.com/SAP/service-fabrik-cli-plugin/errors"
 	"github.com/SAP/service-fabrik-cli-plugin/events"
+	"github.com/SAP/service-fabrik-cli-plugin/guidTranslator"
 	"github.com/SAP/service-fabrik-cli-plugin/helper"
+	"github.com/SAP/service-fabrik-cli-plugin/permission"
 	"github.com/SAP/service-fabrik-cli-plugin/restore"
 	"github.com/cloudfoundry/cli/cf/trace"
 )
@@ -44,11 +50,52 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) Run(cliConnection plugin.CliConn
 	defer func() {
 		if r := recover(); r != nil {
 			fmt.Println(r)
+			if args[0] == "backup-check" {
+				os.Exit(constants.CheckExitUnknown)
+			}
 		}
 	}()
-	argLength := len(args) // Whatever comes after the "cf" word as command are part of args.
+	serviceFabrikPlugin.logger = helper.InitializeTrace() //CF_TRACE traces the direct calls to the broker and UAA.
 	helper.CreateConfFile()
 
+	//--profile selects the conf.json profile instead of the one of the targeted API endpoint.
+	args, profile, err := helper.ExtractFlags(args, []string{"--profile"})
+	if err != nil {
+		invalidArgument(args[0])
+	}
+	if name, flag := profile["--profile"]; flag {
+		helper.SetProfile(name)
+		if _, _, err := helper.GetSelectedProfile(); err != nil && args[0] != "sf-config" && args[0] != "sf-doctor" { //sf-config set creates missing profiles, sf-doctor reports them.
+			fmt.Println(backup.AddColor("FAILED", constants.Red))
+			fmt.Println(err)
+			os.Exit(failureExitCode(args[0], 1))
+		}
+	}
+
+	//--org, --space and --space-guid are accepted by every command and take precedence over the target in config.json.
+	var targetFlags []string = []string{"--org", "--space", "--space-guid"}
+	if args[0] == "clone-service" {
+		targetFlags = []string{"--space-guid"} //clone-service uses --org and --space for the space of the new instance.
+	}
+	args, target, err := helper.ExtractFlags(args, targetFlags)
+	if err != nil {
+		invalidArgument(args[0])
+	}
+	if len(target) > 0 {
+		if _, flag := target["--space-guid"]; flag && (target["--org"] != "" || target["--space"] != "") {
+			invalidArgument(args[0])
+		}
+		targetSpace, err := guidTranslator.ResolveTargetSpace(cliConnection, target["--org"], target["--space"], target["--space-guid"])
+		if err != nil {
+			fmt.Println(backup.AddColor("FAILED", constants.Red))
+			fmt.Println(err)
+			os.Exit(failureExitCode(args[0], 2))
+		}
+		helper.SetTargetSpace(targetSpace)
+	}
+
+	argLength := len(args) // Whatever comes after the "cf" word as command are part of args.
+
 	//Display help text if user enters "cf backup"
 	if argLength == 1 && args[0] == "backup" {
 		serviceFabrikPlugin.printHelp()
@@ -69,12 +116,13 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) Run(cliConnection plugin.CliConn
 		backup.NewBackupCommand(cliConnection).BackupInfo(cliConnection, args[1])
 	}
 
-	if args[0] == "restore" { //If user enters, "cf restore SERVICE_INSTANCE_NAME"
-		if argLength != 2 {
+	if args[0] == "restore" { //If user enters, "cf restore SERVICE_INSTANCE_NAME|--guid INSTANCE_GUID" or "cf restore --guid INSTANCE_GUID"
+		if argLength != 2 && argLength != 3 {
 			errors.IncorrectNumberOfArguments()
 			return
 		}
-		restore.NewRestoreCommand(cliConnection).RestoreInfo(cliConnection, args[1])
+		serviceInstanceName, instanceGuid, inputGuidBool, _ := parseInstanceArguments(args[1:], nil, nil)
+		restore.NewRestoreCommand(cliConnection).RestoreInfo(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 	}
 
 	var cmds []string = strings.Split(args[0], "-")
@@ -83,38 +131,48 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) Run(cliConnection plugin.CliConn
 
 		switch cmds[1] {
 		case "backup":
-			if argLength > 3 {
+			if tooManyBackupArguments(args) {
 				errors.IncorrectNumberOfArguments()
-			} //Error code applicable to all backup commands.
+			}
 			//Internally split into start, abort, list, delete
 			switch cmds[0] {
 			case "start":
-				if argLength != 2 {
-					errors.IncorrectNumberOfArguments()
-				}
+				serviceInstanceName, instanceGuid, inputGuidBool, flags := parseInstanceArguments(args[1:], []string{"--idle-timeout"}, []string{"--wait-for-idle"})
+				waitForIdle, idleTimeout := parseIdleArguments(flags)
+				preflight(cliConnection)
 				fmt.Println("Are you sure you want to start backup? (y/n)")
 				var userChoice string
 				fmt.Scanln(&userChoice)
 				if userChoice == "y" {
-					backup.NewBackupCommand(cliConnection).StartBackup(cliConnection, args[1])
+					backup.NewBackupCommand(cliConnection).StartBackup(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool, waitForIdle, idleTimeout)
 				} else {
 					os.Exit(7)
 				}
 			case "abort":
-				if argLength != 2 {
+				if argLength != 2 && argLength != 3 {
 					errors.IncorrectNumberOfArguments()
 				}
+				serviceInstanceName, instanceGuid, inputGuidBool, _ := parseInstanceArguments(args[1:], nil, nil)
+				preflight(cliConnection)
 				fmt.Println("Are you sure you want to abort backup? (y/n)")
 				var userChoice string
 				fmt.Scanln(&userChoice)
 				if userChoice == "y" {
-					backup.NewBackupCommand(cliConnection).AbortBackup(cliConnection, args[1])
+					backup.NewBackupCommand(cliConnection).AbortBackup(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 				} else {
 					os.Exit(7)
 				}
 
 			//List backup has 2 criteria: listing all backups in space and/or listing all backups of the service-instance name given by user.
 			case "list":
+				if acrossSpaces(args[1:]) {
+					positional, flags, err := helper.ParseArguments(args[1:], nil, []string{"--all-spaces", "--all-orgs", "--no-name"})
+					if err != nil || len(positional) > 0 || flags["--all-spaces"] == flags["--all-orgs"] {
+						errors.InvalidArgument()
+					}
+					backup.NewBackupCommand(cliConnection).ListBackupsAcrossSpaces(cliConnection, flags["--all-orgs"] == "true", flags["--no-name"] == "true")
+					return
+				}
 				if argLength == 2 {
 					if args[1] == "--no-name" {
 						backup.NewBackupCommand(cliConnection).ListBackups(cliConnection, true)
@@ -125,10 +183,17 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) Run(cliConnection plugin.CliConn
 				if argLength == 1 {
 					backup.NewBackupCommand(cliConnection).ListBackups(cliConnection, false)
 				}
-				if argLength == 3 {
-					if args[2] == "--deleted" {
-						backup.NewBackupCommand(cliConnection).ListBackupsByDeletedInstanceName(cliConnection, args[1])
-					} else if args[1] == "--guid" {
+				if argLength >= 3 && args[2] == "--deleted" {
+					positional, flags, err := helper.ParseArguments(args[3:], []string{"--pick"}, []string{"--json"})
+					if err != nil || len(positional) > 0 {
+						errors.InvalidArgument()
+					}
+					if pick, flag := flags["--pick"]; flag && pick != "newest" && pick != "oldest" {
+						errors.InvalidArgument()
+					}
+					backup.NewBackupCommand(cliConnection).ListBackupsByDeletedInstanceName(cliConnection, args[1], flags["--pick"], flags["--json"] == "true")
+				} else if argLength == 3 {
+					if args[1] == "--guid" {
 						backup.NewBackupCommand(cliConnection).ListBackupsByInstance(cliConnection, "", args[2], true)
 					} else {
 						errors.InvalidArgument()
@@ -140,7 +205,7 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) Run(cliConnection plugin.CliConn
 				if argLength != 2 {
 					errors.IncorrectNumberOfArguments()
 				}
-				//Retrieve user_space_guid
+				preflight(cliConnection)
 				fmt.Println("Are you sure you want to delete backup? (y/n)")
 				var userChoice string
 				fmt.Scanln(&userChoice)
@@ -155,71 +220,440 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) Run(cliConnection plugin.CliConn
 			//Internally split into start and abort.
 			switch cmds[0] {
 			case "start":
-				if argLength != 4 {
-					errors.IncorrectNumberOfArguments()
+				serviceInstanceName, instanceGuid, inputGuidBool, flags := parseInstanceArguments(args[1:], []string{"--backup_guid", "--timestamp", "--idle-timeout"}, []string{"--wait-for-idle"})
+				waitForIdle, idleTimeout := parseIdleArguments(flags)
+				backupId, backupIdFlag := flags["--backup_guid"]
+				timeStamp, timeStampFlag := flags["--timestamp"]
+				if backupIdFlag == timeStampFlag {
+					errors.InvalidArgument()
 				}
-				if args[2] == "--backup_guid" {
+				preflight(cliConnection)
+				if backupIdFlag {
 					fmt.Println("Are you sure you want to start restore? (y/n)")
 					var userChoice string
 					fmt.Scanln(&userChoice)
 					if userChoice == "y" {
-						restore.NewRestoreCommand(cliConnection).StartRestore(cliConnection, args[1], args[3], "", true)
+						restore.NewRestoreCommand(cliConnection).StartRestore(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool, backupId, "", true, waitForIdle, idleTimeout)
 					} else {
 						os.Exit(7)
 					}
-				} else if args[2] == "--timestamp" {
+				} else {
 					fmt.Println("Are you sure you want to start restore? (y/n)")
 					var userChoice string
 					fmt.Scanln(&userChoice)
 					if userChoice == "y" {
-						restore.NewRestoreCommand(cliConnection).StartRestore(cliConnection, args[1], "", args[3], false)
+						restore.NewRestoreCommand(cliConnection).StartRestore(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool, "", timeStamp, false, waitForIdle, idleTimeout)
 					} else {
 						os.Exit(7)
 					}
-				} else {
-					errors.InvalidArgument()
 				}
 			case "abort":
-				if argLength != 2 {
+				if argLength != 2 && argLength != 3 {
 					errors.IncorrectNumberOfArguments()
 				}
+				serviceInstanceName, instanceGuid, inputGuidBool, _ := parseInstanceArguments(args[1:], nil, nil)
+				preflight(cliConnection)
 				fmt.Println("Are you sure you want to start backup? (y/n)")
 				var userChoice string
 				fmt.Scanln(&userChoice)
 				if userChoice == "y" {
-					restore.NewRestoreCommand(cliConnection).AbortRestore(cliConnection, args[1])
+					restore.NewRestoreCommand(cliConnection).AbortRestore(cliConnection, serviceInstanceName, instanceGuid, inputGuidBool)
 				} else {
 					os.Exit(7)
 				}
 			}
-		case "events":
+		case "backups":
+			switch cmds[0] {
+			case "prune":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--keep-last", "--older-than"}, []string{"--only-on-demand", "--confirm"})
+				if err != nil || len(positional) > 1 {
+					errors.InvalidArgument()
+				}
+				_, keepLastFlag := flags["--keep-last"]
+				_, olderThanFlag := flags["--older-than"]
+				if keepLastFlag == olderThanFlag {
+					errors.InvalidArgument()
+				}
+				var keepLast int
+				var olderThan time.Duration
+				if keepLastFlag {
+					keepLast, err = strconv.Atoi(flags["--keep-last"])
+					if err != nil || keepLast < 1 {
+						errors.InvalidArgument()
+					}
+				} else {
+					olderThan, err = helper.ParseDuration(flags["--older-than"])
+					if err != nil || olderThan <= 0 {
+						errors.InvalidArgument()
+					}
+				}
+				var serviceInstanceName string
+				if len(positional) == 1 {
+					serviceInstanceName = positional[0]
+				}
+				if flags["--confirm"] == "true" {
+					preflight(cliConnection)
+				}
+				backup.NewBackupCommand(cliConnection).PruneBackups(cliConnection, serviceInstanceName, keepLast, olderThan, flags["--only-on-demand"] == "true", flags["--confirm"] == "true")
+			case "orphaned":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--prune-older-than"}, []string{"--json"})
+				if err != nil || len(positional) > 0 {
+					errors.InvalidArgument()
+				}
+				var pruneOlderThan time.Duration
+				if value, flag := flags["--prune-older-than"]; flag {
+					pruneOlderThan, err = helper.ParseDuration(value)
+					if err != nil || pruneOlderThan <= 0 || flags["--json"] == "true" {
+						errors.InvalidArgument()
+					}
+					preflight(cliConnection)
+				}
+				backup.NewBackupCommand(cliConnection).ListOrphanedBackups(cliConnection, flags["--json"] == "true", pruneOlderThan)
+			}
+		case "stats":
+			switch cmds[0] {
+			case "backup":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--window", "--trend"}, nil)
+				if err != nil || len(positional) > 1 {
+					errors.InvalidArgument()
+				}
+				var window time.Duration = 30 * 24 * time.Hour
+				if value, flag := flags["--window"]; flag {
+					window, err = helper.ParseDuration(value)
+					if err != nil || window <= 0 {
+						errors.InvalidArgument()
+					}
+				}
+				var trendLength int = 10
+				if value, flag := flags["--trend"]; flag {
+					trendLength, err = strconv.Atoi(value)
+					if err != nil || trendLength < 1 {
+						errors.InvalidArgument()
+					}
+				}
+				var serviceInstanceName string
+				if len(positional) == 1 {
+					serviceInstanceName = positional[0]
+				}
+				backup.NewBackupCommand(cliConnection).ShowBackupStats(cliConnection, serviceInstanceName, window, trendLength)
+			}
+		case "metrics":
+			switch cmds[0] {
+			case "backup":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--textfile"}, nil)
+				if err != nil || len(positional) > 0 || flags["--textfile"] == "" {
+					errors.InvalidArgument()
+				}
+				backup.NewBackupCommand(cliConnection).WriteBackupMetrics(cliConnection, flags["--textfile"])
+			}
+		case "check":
+			switch cmds[0] {
+			case "backup":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--max-age", "--require-type"}, []string{"--all", "--json"})
+				if err != nil || len(positional) > 1 || (len(positional) == 1) == (flags["--all"] == "true") {
+					invalidArgument(args[0])
+				}
+				maxAge, err := helper.ParseDuration(flags["--max-age"])
+				if err != nil || maxAge <= 0 {
+					invalidArgument(args[0])
+				}
+				if requireType := flags["--require-type"]; requireType != "" && requireType != "online" && requireType != "offline" {
+					invalidArgument(args[0])
+				}
+				var serviceInstanceName string
+				if len(positional) == 1 {
+					serviceInstanceName = positional[0]
+				}
+				os.Exit(backup.NewBackupCommand(cliConnection).BackupCheck(cliConnection, serviceInstanceName, maxAge, flags["--require-type"], flags["--json"] == "true"))
+			}
+		case "service":
+			switch cmds[0] {
+			case "clone":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--backup_guid", "--timestamp", "--org", "--space", "--plan"}, []string{"--latest"})
+				if err != nil || len(positional) != 2 {
+					errors.InvalidArgument()
+				}
+				if _, flag := flags["--space"]; !flag && flags["--org"] != "" {
+					errors.InvalidArgument() //--org names the org of --space.
+				}
+				var selectors int = 0
+				for _, flag := range []string{"--backup_guid", "--timestamp", "--latest"} {
+					if _, present := flags[flag]; present {
+						selectors++
+					}
+				}
+				if selectors > 1 {
+					errors.InvalidArgument()
+				}
+				//The new instance is created in the space given by --org and --space, which is where the role is needed.
+				targetSpace := helper.TargetedSpace(helper.ReadConfigJsonFile())
+				if spaceName, flag := flags["--space"]; flag {
+					targetSpace, err = guidTranslator.ResolveTargetSpace(cliConnection, flags["--org"], spaceName, "")
+					if err != nil {
+						fmt.Println(backup.AddColor("FAILED", constants.Red))
+						fmt.Println(err)
+						os.Exit(2)
+					}
+				}
+				permission.Preflight(cliConnection, targetSpace)
+				fmt.Println("Are you sure you want to clone the service instance? (y/n)")
+				var userChoice string
+				fmt.Scanln(&userChoice)
+				if userChoice == "y" {
+					restore.NewRestoreCommand(cliConnection).CloneService(cliConnection, positional[0], positional[1], flags["--backup_guid"], flags["--timestamp"], targetSpace, flags["--plan"])
+				} else {
+					os.Exit(7)
+				}
+			}
+		case "instance":
+			switch cmds[0] {
+			case "recover":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--guid", "--new-name", "--backup_guid"}, nil)
+				if err != nil || len(positional) != 1 {
+					errors.InvalidArgument()
+				}
+				preflight(cliConnection)
+				fmt.Println("Are you sure you want to recover the deleted instance? (y/n)")
+				var userChoice string
+				fmt.Scanln(&userChoice)
+				if userChoice == "y" {
+					restore.NewRestoreCommand(cliConnection).RecoverInstance(cliConnection, positional[0], flags["--guid"], flags["--new-name"], flags["--backup_guid"])
+				} else {
+					os.Exit(7)
+				}
+			}
+		case "instances":
+			switch cmds[0] {
+			case "deleted":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--since"}, nil)
+				if err != nil || len(positional) > 0 {
+					errors.InvalidArgument()
+				}
+				var since time.Time
+				if value, flag := flags["--since"]; flag {
+					since, err = helper.ParseSince(value, time.Now())
+					if err != nil {
+						errors.InvalidArgument()
+					}
+				}
+				backup.NewBackupCommand(cliConnection).ListDeletedInstances(cliConnection, since)
+			}
+		case "timeline":
 			switch cmds[0] {
 			case "instance":
-				if argLength > 2 {
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--since"}, []string{"--json"})
+				if err != nil || len(positional) != 1 {
+					errors.InvalidArgument()
+				}
+				var since time.Time
+				if value, flag := flags["--since"]; flag {
+					since, err = helper.ParseSince(value, time.Now())
+					if err != nil {
+						errors.InvalidArgument()
+					}
+				}
+				events.NewEventsCommand(cliConnection).ShowInstanceTimeline(cliConnection, positional[0], since, flags["--json"] == "true")
+			}
+		case "config":
+			switch cmds[0] {
+			case "sf":
+				positional, _, err := helper.ParseArguments(args[1:], nil, nil)
+				if err != nil || len(positional) == 0 {
+					errors.InvalidArgument()
+				}
+				configCommand := config.NewConfigCommand(cliConnection)
+				switch {
+				case positional[0] == "get" && len(positional) == 2:
+					configCommand.Get(positional[1])
+				case positional[0] == "set" && len(positional) == 3:
+					configCommand.Set(positional[1], positional[2])
+				case positional[0] == "unset" && len(positional) == 2:
+					configCommand.Unset(positional[1])
+				case positional[0] == "list" && len(positional) == 1:
+					configCommand.List()
+				case positional[0] == "validate" && len(positional) == 1:
+					configCommand.Validate()
+				default:
+					errors.InvalidArgument()
+				}
+			}
+		case "doctor":
+			switch cmds[0] {
+			case "sf":
+				positional, flags, err := helper.ParseArguments(args[1:], nil, []string{"--json"})
+				if err != nil || len(positional) != 0 {
+					errors.InvalidArgument()
+				}
+				doctor.NewDoctorCommand(cliConnection).RunDoctor(cliConnection, flags["--json"] == "true")
+			}
+		case "whoami":
+			switch cmds[0] {
+			case "sf":
+				if argLength != 1 {
 					errors.IncorrectNumberOfArguments()
 				}
-				if argLength == 2 {
-					if args[1] == "--delete" {
-						events.NewEventsCommand(cliConnection).ListEvents(cliConnection, true, "delete")
-					} else if args[1] == "--create" {
-						events.NewEventsCommand(cliConnection).ListEvents(cliConnection, true, "create")
-					} else if args[1] == "--update" {
-						events.NewEventsCommand(cliConnection).ListEvents(cliConnection, true, "update")
-					} else {
+				permission.NewPermissionCommand(cliConnection).Whoami(cliConnection)
+			}
+		case "names":
+			switch cmds[0] {
+			case "instance":
+				positional, flags, err := helper.ParseArguments(args[1:], nil, []string{"--json"})
+				if err != nil || len(positional) != 1 {
+					errors.InvalidArgument()
+				}
+				events.NewEventsCommand(cliConnection).ShowInstanceNames(cliConnection, positional[0], flags["--json"] == "true")
+			}
+		case "events":
+			switch cmds[0] {
+			case "instance":
+				positional, flags, err := helper.ParseArguments(args[1:], []string{"--instance", "--user", "--since", "--until", "--limit", "--types", "--interval"}, []string{"--delete", "--create", "--update", "--details", "--follow", "--json"})
+				if err != nil || len(positional) != 0 {
+					errors.InvalidArgument()
+				}
+				var filter events.EventFilter
+				for _, action := range []string{"create", "update", "delete"} {
+					if flags["--"+action] == "true" {
+						if filter.Action != "" {
+							errors.InvalidArgument()
+						}
+						filter.Action = action
+					}
+				}
+				filter.User = flags["--user"]
+				if value, flag := flags["--since"]; flag {
+					if filter.Since, err = helper.ParseSince(value, time.Now()); err != nil {
 						errors.InvalidArgument()
-						serviceFabrikPlugin.printHelp()
-						return
 					}
 				}
-				if argLength == 1 {
-					events.NewEventsCommand(cliConnection).ListEvents(cliConnection, true, "")
+				if value, flag := flags["--until"]; flag {
+					if filter.Until, err = helper.ParseSince(value, time.Now()); err != nil {
+						errors.InvalidArgument()
+					}
 				}
-
+				if value, flag := flags["--limit"]; flag {
+					if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 {
+						errors.InvalidArgument()
+					}
+				}
+				if filter.Groups, err = events.ParseEventGroups(flags["--types"]); err != nil {
+					fmt.Println(err)
+					errors.InvalidArgument()
+				}
+				if value, flag := flags["--instance"]; flag {
+					filter.InstanceGuid, err = events.ResolveEventInstance(cliConnection, helper.GetSpaceGUID(helper.ReadConfigJsonFile()), value)
+					if err != nil {
+						fmt.Println(events.AddColor("FAILED", constants.Red))
+						fmt.Println(err)
+						os.Exit(1)
+					}
+				}
+				if flags["--follow"] == "true" {
+					var interval time.Duration = time.Duration(constants.FollowInterval) * time.Second
+					if value, flag := flags["--interval"]; flag {
+						if interval, err = helper.ParseDuration(value); err != nil || interval <= 0 {
+							errors.InvalidArgument()
+						}
+					}
+					if filter.Limit > 0 || !filter.Until.IsZero() || flags["--details"] == "true" {
+						errors.InvalidArgument()
+					}
+					events.NewEventsCommand(cliConnection).FollowEvents(cliConnection, filter, interval, flags["--json"] == "true")
+					return
+				}
+				if _, flag := flags["--interval"]; flag || flags["--json"] == "true" {
+					errors.InvalidArgument()
+				}
+				events.NewEventsCommand(cliConnection).ListEvents(cliConnection, true, filter, flags["--details"] == "true")
 			}
 		}
 	}
 }
 
+// tooManyBackupArguments reports whether a backup command has more arguments than it accepts. start-backup and
+// list-backup --deleted take further flags and are checked by their own parsing.
+func tooManyBackupArguments(args []string) bool {
+	if len(args) <= 3 || strings.HasPrefix(args[0], "start-") {
+		return false
+	}
+	return !(strings.HasPrefix(args[0], "list-") && args[2] == "--deleted")
+}
+
+// failureExitCode returns the exit code of a command failing with code. backup-check fails with constants.CheckExitUnknown,
+// as monitoring relies on it exiting only with 0, 2 or 3.
+func failureExitCode(command string, code int) int {
+	if command == "backup-check" {
+		return constants.CheckExitUnknown
+	}
+	return code
+}
+
+// invalidArgument fails the command like errors.InvalidArgument, with the exit code given by failureExitCode.
+func invalidArgument(command string) {
+	if failureExitCode(command, 1) == 1 {
+		errors.InvalidArgument()
+	}
+	fmt.Println(backup.AddColor("FAILED", constants.Red))
+	fmt.Println("You have entered an invalid argument.")
+	fmt.Println("Enter 'cf backup' to check the list of commands and their usage.")
+	os.Exit(failureExitCode(command, 1))
+}
+
+// acrossSpaces reports whether list-backup is asked for the backups of many spaces, by --all-spaces or --all-orgs in any position.
+func acrossSpaces(args []string) bool {
+	for _, arg := range args {
+		if arg == "--all-spaces" || arg == "--all-orgs" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitInstanceArguments splits the arguments of an instance-scoped command, which names the instance either by
+// SERVICE_INSTANCE_NAME or by --guid INSTANCE_GUID, into the name, the guid, whether the guid was given and the other
+// flags. Unknown flags and extra arguments are errors.
+func splitInstanceArguments(args []string, valueFlags []string, boolFlags []string) (string, string, bool, map[string]string, error) {
+	positional, flags, err := helper.ParseArguments(args, append([]string{"--guid"}, valueFlags...), boolFlags)
+	if err != nil {
+		return "", "", false, nil, err
+	}
+	instanceGuid, inputGuidBool := flags["--guid"]
+	if (inputGuidBool && len(positional) != 0) || (!inputGuidBool && len(positional) != 1) {
+		return "", "", false, nil, fmt.Errorf("expected either SERVICE_INSTANCE_NAME or --guid INSTANCE_GUID")
+	}
+	if inputGuidBool {
+		return "", instanceGuid, true, flags, nil
+	}
+	return positional[0], "", false, flags, nil
+}
+
+// parseInstanceArguments is splitInstanceArguments which exits on invalid arguments.
+func parseInstanceArguments(args []string, valueFlags []string, boolFlags []string) (string, string, bool, map[string]string) {
+	serviceInstanceName, instanceGuid, inputGuidBool, flags, err := splitInstanceArguments(args, valueFlags, boolFlags)
+	if err != nil {
+		errors.InvalidArgument()
+	}
+	return serviceInstanceName, instanceGuid, inputGuidBool, flags
+}
+
+// preflight checks the permissions for a command which changes backups or service instances of the targeted space.
+// Instances are only resolved in the targeted space, so it is also the space of the instance the command acts on.
+func preflight(cliConnection plugin.CliConnection) {
+	permission.Preflight(cliConnection, helper.TargetedSpace(helper.ReadConfigJsonFile()))
+}
+
+// parseIdleArguments returns whether to wait until the instance is idle and for how long. --idle-timeout requires --wait-for-idle.
+func parseIdleArguments(flags map[string]string) (bool, time.Duration) {
+	var idleTimeout time.Duration = time.Duration(constants.OperationTimeout) * time.Second
+	value, timeoutFlag := flags["--idle-timeout"]
+	if timeoutFlag {
+		var err error
+		if idleTimeout, err = helper.ParseDuration(value); err != nil || idleTimeout <= 0 || flags["--wait-for-idle"] != "true" {
+			errors.InvalidArgument()
+		}
+	}
+	return flags["--wait-for-idle"] == "true", idleTimeout
+}
+
 func (c *ServiceFabrikPlugin) printHelp() {
 	metadata := c.GetMetadata()
 	for _, command := range metadata.Commands {
@@ -238,30 +672,107 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) GetMetadata() plugin.PluginMetad
 		Commands: []plugin.Command{
 			/*{ // required to be a registered command
 				Name:     "start-backup",
-				HelpText: "Start backup of a service instance",
+				HelpText: "Start backup of a service instance, given by name or by --guid",
 				UsageDetails: plugin.Usage{
-					Usage: "cf start-backup SERVICE_INSTANCE_NAME",
+					Usage: "cf start-backup SERVICE_INSTANCE_NAME|--guid INSTANCE_GUID [--wait-for-idle [--idle-timeout DURATION]]",
 				},
 			},
 			{
 				Name:     "abort-backup",
-				HelpText: "Abort backup of a service instance",
+				HelpText: "Abort backup of a service instance, given by name or by --guid",
 				UsageDetails: plugin.Usage{
-					Usage: "cf abort-backup SERVICE_INSTANCE_NAME",
+					Usage: "cf abort-backup SERVICE_INSTANCE_NAME|--guid INSTANCE_GUID",
 				},
 			},*/
 			{
 				Name:     "list-backup",
 				HelpText: "List backup(s) of a service instance",
 				UsageDetails: plugin.Usage{
-					Usage: "cf list-backup [SERVICE_INSTANCE_NAME] \n    cf list-backup [SERVICE_INSTANCE_NAME] --deleted \n    cf list-backup  --guid INSTANCE_GUID",
+					Usage: "cf list-backup [SERVICE_INSTANCE_NAME] \n    cf list-backup --all-spaces|--all-orgs [--no-name] \n    cf list-backup [SERVICE_INSTANCE_NAME] --deleted [--pick newest|oldest] [--json] \n    cf list-backup  --guid INSTANCE_GUID",
+				},
+			},
+			{
+				Name:     "prune-backups",
+				HelpText: "Delete old backups according to a retention rule (dry run unless --confirm is given)",
+				UsageDetails: plugin.Usage{
+					Usage: "cf prune-backups [SERVICE_INSTANCE_NAME] --keep-last N [--only-on-demand] [--confirm] \n    cf prune-backups [SERVICE_INSTANCE_NAME] --older-than DURATION [--only-on-demand] [--confirm]",
+				},
+			},
+			{
+				Name:     "orphaned-backups",
+				HelpText: "List backups of service instances which no longer exist",
+				UsageDetails: plugin.Usage{
+					Usage: "cf orphaned-backups [--json] \n    cf orphaned-backups --prune-older-than DURATION",
+				},
+			},
+			{
+				Name:     "backup-check",
+				HelpText: "Check that the newest successful backup of service instances is not older than a maximum age",
+				UsageDetails: plugin.Usage{
+					Usage: "cf backup-check SERVICE_INSTANCE_NAME|--all --max-age DURATION [--require-type online|offline] [--json]",
+				},
+			},
+			{
+				Name:     "backup-metrics",
+				HelpText: "Write backup metrics of the service instances in Prometheus text format",
+				UsageDetails: plugin.Usage{
+					Usage: "cf backup-metrics --textfile PATH",
+				},
+			},
+			{
+				Name:     "backup-stats",
+				HelpText: "Show backup count, success rate and duration statistics of service instances",
+				UsageDetails: plugin.Usage{
+					Usage: "cf backup-stats [SERVICE_INSTANCE_NAME] [--window DURATION] [--trend N]",
+				},
+			},
+			{
+				Name:     "deleted-instances",
+				HelpText: "List deleted service instances which still have backups",
+				UsageDetails: plugin.Usage{
+					Usage: "cf deleted-instances [--since DURATION|TIME_STAMP]",
 				},
 			},
 			{
 				Name:     "instance-events",
 				HelpText: "List events for service instances",
 				UsageDetails: plugin.Usage{
-					Usage: "cf instance-events [--delete|--create|--update]",
+					Usage: "cf instance-events [--delete|--create|--update] [--instance NAME|GUID] [--user USER] [--since DURATION|TIME_STAMP] [--until DURATION|TIME_STAMP] [--limit N] [--types bindings,keys,route-bindings] [--details] \n    cf instance-events --follow [--delete|--create|--update] [--instance NAME|GUID] [--user USER] [--since DURATION|TIME_STAMP] [--types bindings,keys,route-bindings] [--interval DURATION] [--json]",
+				},
+			},
+			{
+				Name:     "instance-timeline",
+				HelpText: "Show the events, backups and restores of a service instance in chronological order",
+				UsageDetails: plugin.Usage{
+					Usage: "cf instance-timeline SERVICE_INSTANCE_NAME [--since DURATION|TIME_STAMP] [--json]",
+				},
+			},
+			{
+				Name:     "instance-names",
+				HelpText: "Show all names a service instance had, also a deleted one",
+				UsageDetails: plugin.Usage{
+					Usage: "cf instance-names SERVICE_INSTANCE_GUID [--json]",
+				},
+			},
+			{
+				Name:     "sf-config",
+				HelpText: "Show, change or validate the plugin configuration in conf.json",
+				UsageDetails: plugin.Usage{
+					Usage: "cf sf-config get|unset KEY [--profile PROFILE] \n    cf sf-config set KEY VALUE [--profile PROFILE] \n    cf sf-config list|validate [--profile PROFILE]",
+				},
+			},
+			{
+				Name:     "sf-whoami",
+				HelpText: "Show the user, the roles in the targeted org and space and the expiry of the token",
+				UsageDetails: plugin.Usage{
+					Usage: "cf sf-whoami",
+				},
+			},
+			{
+				Name:     "sf-doctor",
+				HelpText: "Check the login, the plugin configuration and the connection to the broker and the cloud controller",
+				UsageDetails: plugin.Usage{
+					Usage: "cf sf-doctor [--profile PROFILE] [--json]",
 				},
 			},
 			/*{
@@ -280,23 +791,37 @@ func (serviceFabrikPlugin *ServiceFabrikPlugin) GetMetadata() plugin.PluginMetad
 			},
 			{
 				Name:     "start-restore",
-				HelpText: "Start restore of a service instance",
+				HelpText: "Start restore of a service instance, given by name or by --guid",
 				UsageDetails: plugin.Usage{
-					Usage: "cf start-restore SERVICE_INSTANCE_NAME --backup_guid BACKUP_ID \n     cf start-restore SERVICE_INSTANCE_NAME --timestamp TIME_STAMP",
+					Usage: "cf start-restore SERVICE_INSTANCE_NAME|--guid INSTANCE_GUID --backup_guid BACKUP_ID [--wait-for-idle [--idle-timeout DURATION]] \n     cf start-restore SERVICE_INSTANCE_NAME|--guid INSTANCE_GUID --timestamp TIME_STAMP [--wait-for-idle [--idle-timeout DURATION]]",
 				},
 			},
 			{
 				Name:     "restore",
-				HelpText: "Status of the last Restore operation of a service-instance",
+				HelpText: "Status of the last Restore operation of a service-instance, given by name or by --guid",
 				UsageDetails: plugin.Usage{
-					Usage: "cf restore SERVICE_INSTANCE_NAME",
+					Usage: "cf restore SERVICE_INSTANCE_NAME|--guid INSTANCE_GUID",
 				},
 			},
 			{
 				Name:     "abort-restore",
-				HelpText: "Abort restore of a service instance",
+				HelpText: "Abort restore of a service instance, given by name or by --guid",
+				UsageDetails: plugin.Usage{
+					Usage: "cf abort-restore SERVICE_INSTANCE_NAME|--guid INSTANCE_GUID",
+				},
+			},
+			{
+				Name:     "clone-service",
+				HelpText: "Create a new service instance from a backup of an existing one",
+				UsageDetails: plugin.Usage{
+					Usage: "cf clone-service SOURCE_INSTANCE_NAME NEW_INSTANCE_NAME [--backup_guid BACKUP_ID | --latest | --timestamp TIME_STAMP] [--org ORG_NAME] [--space SPACE_NAME] [--plan PLAN_NAME]",
+				},
+			},
+			{
+				Name:     "recover-instance",
+				HelpText: "Recreate a deleted service instance and restore it from one of its backups",
 				UsageDetails: plugin.Usage{
-					Usage: "cf abort-restore SERVICE_INSTANCE_NAME",
+					Usage: "cf recover-instance DELETED_INSTANCE_NAME [--guid OLD_INSTANCE_GUID] [--new-name NEW_INSTANCE_NAME] [--backup_guid BACKUP_ID]",
 				},
 			},
 		},
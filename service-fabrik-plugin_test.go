@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestPlugin(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Plugin Suite")
+}
+
+var _ = Describe("dispatch", func() {
+	startBackupFlags := []string{"--idle-timeout"}
+	idleFlags := []string{"--wait-for-idle"}
+
+	It("start-backup should accept the advertised invocations", func() {
+		for _, args := range [][]string{
+			{"start-backup", "NAME"},
+			{"start-backup", "NAME", "--wait-for-idle"},
+			{"start-backup", "NAME", "--wait-for-idle", "--idle-timeout", "30m"},
+			{"start-backup", "--guid", "GUID"},
+			{"start-backup", "--guid", "GUID", "--wait-for-idle"},
+			{"start-backup", "--guid", "GUID", "--wait-for-idle", "--idle-timeout", "30m"},
+		} {
+			Expect(tooManyBackupArguments(args)).To(BeFalse(), "%v", args)
+			_, _, _, _, err := splitInstanceArguments(args[1:], startBackupFlags, idleFlags)
+			Expect(err).NotTo(HaveOccurred(), "%v", args)
+		}
+		name, guid, inputGuid, flags, _ := splitInstanceArguments([]string{"NAME", "--wait-for-idle", "--idle-timeout", "30m"}, startBackupFlags, idleFlags)
+		Expect([]interface{}{name, guid, inputGuid}).To(Equal([]interface{}{"NAME", "", false}))
+		Expect(flags).To(Equal(map[string]string{"--wait-for-idle": "true", "--idle-timeout": "30m"}))
+	})
+	It("start-backup should reject unknown flags and extra arguments", func() {
+		for _, args := range [][]string{
+			{"NAME", "OTHER"},
+			{"NAME", "--guid", "GUID"},
+			{"NAME", "--force"},
+			{"--guid"},
+			{},
+		} {
+			_, _, _, _, err := splitInstanceArguments(args, startBackupFlags, idleFlags)
+			Expect(err).To(HaveOccurred(), "%v", args)
+		}
+	})
+	It("Other backup commands should keep the limit of their arguments", func() {
+		Expect(tooManyBackupArguments([]string{"abort-backup", "--guid", "GUID", "EXTRA"})).To(BeTrue())
+		Expect(tooManyBackupArguments([]string{"list-backup", "NAME", "--deleted", "--json"})).To(BeFalse())
+		Expect(tooManyBackupArguments([]string{"list-backup", "NAME", "--json", "EXTRA"})).To(BeTrue())
+	})
+	It("list-backup should find --all-spaces and --all-orgs in any position", func() {
+		Expect(acrossSpaces([]string{"--no-name", "--all-spaces"})).To(BeTrue())
+		Expect(acrossSpaces([]string{"--all-orgs"})).To(BeTrue())
+		Expect(acrossSpaces([]string{"NAME", "--deleted"})).To(BeFalse())
+	})
+})